@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/henrytill/hbt-go/internal"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func showDedupeUsage(flags *flag.FlagSet) {
+	fmt.Printf("Usage: %s dedupe [OPTIONS] FILE[:FORMAT]...\n\n", os.Args[0])
+	fmt.Println("Find entities whose URLs differ only in ways the --rules ignore")
+	fmt.Println("\nEach cluster is listed with the URL that would be kept first. With --apply,")
+	fmt.Println("each cluster is absorbed into its first entity and the result written to the")
+	fmt.Println("output format, with the clusters listed on stderr.")
+	fmt.Printf("\nRules: %s. Several are separated by commas.\n", urlRules())
+	fmt.Println("\nOptions:")
+	flags.PrintDefaults()
+}
+
+func urlRules() string {
+	names := make([]string, len(types.URLRules))
+	for i, rule := range types.URLRules {
+		names[i] = string(rule)
+	}
+	return strings.Join(names, ", ")
+}
+
+func runDedupe(args []string) {
+	flags := flag.NewFlagSet("dedupe", flag.ExitOnError)
+
+	inputFormat := internal.NewInputFormatFlag()
+	outputFormat := internal.NewOutputFormatFlag()
+	fromUsage := fmt.Sprintf("Input format of files that do not name theirs (%s)", inputFormats())
+	toUsage := fmt.Sprintf("Output format with --apply (%s)", outputFormats())
+	flags.Var(&inputFormat, "f", fromUsage)
+	flags.Var(&inputFormat, "from", fromUsage)
+	flags.Var(&outputFormat, "t", toUsage)
+	flags.Var(&outputFormat, "to", toUsage)
+	rulesArg := flags.String("rules", "all", "Differences between URLs to ignore (all, none, or a list of rules)")
+	apply := flags.Bool("apply", false, "Absorb each cluster into its first entity and write the result")
+	outputFile := flags.String("o", "", "Output file with --apply (defaults to stdout)")
+	contradict := flags.String("contradictions", "false", "Write flags that sources disagree on as false, true, or unset, or refuse to write them")
+	flags.Usage = func() { showDedupeUsage(flags) }
+
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Error: No input files specified\n\n")
+		showDedupeUsage(flags)
+		os.Exit(1)
+	}
+
+	rules, err := types.ParseURLRules(*rulesArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	normalize := types.NewNormalizer(rules...)
+
+	var opts internal.Options
+	opts.Contradictions, err = types.ParseContradictionPolicy(*contradict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *apply {
+		if outputFormat.Format.Name == "" && *outputFile != "" {
+			format, err := detectOutputFormat(*outputFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			outputFormat.Format = format
+		}
+		if outputFormat.Format.Name == "" {
+			fmt.Fprintf(os.Stderr, "Error: Must specify an output format (-t) with --apply\n")
+			os.Exit(1)
+		}
+	}
+
+	var coll types.Collection
+	usedStdin := false
+	for i, arg := range flags.Args() {
+		filename, format, err := internal.ParseInputArg(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", arg, err)
+			os.Exit(1)
+		}
+		if format.Name == "" {
+			format = inputFormat.Format
+		}
+		if filename == stdinName {
+			if usedStdin {
+				fmt.Fprintf(os.Stderr, "Error: stdin (-) can be read only once\n")
+				os.Exit(1)
+			}
+			usedStdin = true
+		}
+
+		parsed, _, err := readInput(inputSpec{filename: filename, format: format}, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if i == 0 {
+			coll = parsed
+		} else {
+			coll.Merge(&parsed)
+		}
+	}
+
+	clusters := coll.Duplicates(normalize)
+	uris := make(map[types.Id]string, coll.Len())
+	for id, entity := range coll.Nodes() {
+		uris[id] = entity.URI.String()
+	}
+
+	if !*apply {
+		for _, cluster := range clusters {
+			writeCluster(os.Stdout, uris, cluster)
+		}
+		fmt.Printf("Clusters: %d (%d entities to absorb)\n", len(clusters), absorbable(clusters))
+		return
+	}
+
+	for _, cluster := range clusters {
+		writeCluster(os.Stderr, uris, cluster)
+	}
+
+	deduped := coll.Dedupe(normalize)
+	listContradictions(&deduped, outputFormat.Format, opts.Contradictions)
+
+	output, closeOutput := createOutput(*outputFile)
+	if err := internal.Unparse(outputFormat.Format, output, &deduped, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+	closeOutput()
+}
+
+// writeCluster writes the URL of the entity a cluster would be absorbed into,
+// then the URLs of the others, indented.
+func writeCluster(w io.Writer, uris map[types.Id]string, cluster []types.Id) {
+	for i, id := range cluster {
+		indent := ""
+		if i > 0 {
+			indent = "  "
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, uris[id])
+	}
+}
+
+// absorbable counts the entities clusters would absorb into others.
+func absorbable(clusters [][]types.Id) int {
+	n := 0
+	for _, cluster := range clusters {
+		n += len(cluster) - 1
+	}
+	return n
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+	"github.com/henrytill/hbt-go/internal"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// As with diff(1), hbt diff exits with status 1 when the sources differ and
+// 2 when they could not be compared.
+const (
+	diffExitDiffer  = 1
+	diffExitTrouble = 2
+)
+
+func showDiffUsage(flags *flag.FlagSet) {
+	fmt.Printf("Usage: %s diff [OPTIONS] OLD[:FORMAT] NEW[:FORMAT]\n\n", os.Args[0])
+	fmt.Println("Report the entities added, removed, and changed between two sources")
+	fmt.Println("\nExits with status 0 if the sources match, 1 if they differ, and 2 on error.")
+	fmt.Println("\nOptions:")
+	flags.PrintDefaults()
+}
+
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+
+	inputFormat := internal.NewInputFormatFlag()
+	fromUsage := fmt.Sprintf("Input format of both sources (%s)", inputFormats())
+	flags.Var(&inputFormat, "f", fromUsage)
+	flags.Var(&inputFormat, "from", fromUsage)
+	report := flags.String("t", "text", "Report format (text, json, yaml)")
+	flags.Usage = func() { showDiffUsage(flags) }
+
+	// ExitOnError exits with status 2, as a trouble status should.
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Error: exactly two sources required, got %d\n\n", flags.NArg())
+		showDiffUsage(flags)
+		os.Exit(diffExitTrouble)
+	}
+
+	var writeReport func(io.Writer, types.Diff) error
+	switch *report {
+	case "text":
+		writeReport = writeDiffText
+	case "json":
+		writeReport = writeDiffJSON
+	case "yaml":
+		writeReport = writeDiffYAML
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid report format: %s\n", *report)
+		os.Exit(diffExitTrouble)
+	}
+
+	var colls [2]types.Collection
+	usedStdin := false
+	for i, arg := range flags.Args() {
+		filename, format, err := internal.ParseInputArg(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", arg, err)
+			os.Exit(diffExitTrouble)
+		}
+		if format.Name == "" {
+			format = inputFormat.Format
+		}
+		if filename == stdinName {
+			if usedStdin {
+				fmt.Fprintf(os.Stderr, "Error: stdin (-) can be read only once\n")
+				os.Exit(diffExitTrouble)
+			}
+			usedStdin = true
+		}
+
+		colls[i], _, err = readInput(inputSpec{filename: filename, format: format}, internal.Options{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(diffExitTrouble)
+		}
+	}
+
+	d := colls[0].Diff(&colls[1])
+
+	output := bufio.NewWriter(os.Stdout)
+	if err := writeReport(output, d); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(diffExitTrouble)
+	}
+	if err := output.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(diffExitTrouble)
+	}
+
+	if !d.Empty() {
+		os.Exit(diffExitDiffer)
+	}
+}
+
+// quoteDiffValue quotes a value that would be hard to read bare in a text
+// report.
+func quoteDiffValue(v string) string {
+	if v == "" || strings.ContainsFunc(v, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// writeDiffText writes one line per added (+), removed (-), and changed (~)
+// URL, with a line under each changed URL for each changed field.
+func writeDiffText(w io.Writer, d types.Diff) error {
+	for _, uri := range d.Added {
+		if _, err := fmt.Fprintf(w, "+ %s\n", uri); err != nil {
+			return err
+		}
+	}
+	for _, uri := range d.Removed {
+		if _, err := fmt.Fprintf(w, "- %s\n", uri); err != nil {
+			return err
+		}
+	}
+	for _, changed := range d.Changed {
+		if _, err := fmt.Fprintf(w, "~ %s\n", changed.URI); err != nil {
+			return err
+		}
+		for _, change := range changed.Changes {
+			var values []string
+			for _, v := range change.Removed {
+				values = append(values, "-"+quoteDiffValue(v))
+			}
+			for _, v := range change.Added {
+				values = append(values, "+"+quoteDiffValue(v))
+			}
+			if _, err := fmt.Fprintf(w, "    %s: %s\n", change.Field, strings.Join(values, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeDiffJSON(w io.Writer, d types.Diff) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+func writeDiffYAML(w io.Writer, d types.Diff) error {
+	encoder := yaml.NewEncoder(w,
+		yaml.UseSingleQuote(true),
+		yaml.Indent(2),
+	)
+	defer encoder.Close()
+
+	return encoder.Encode(d)
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
+	"iter"
 	"maps"
 	"os"
 	"path/filepath"
@@ -12,6 +15,10 @@ import (
 	"strings"
 
 	"github.com/henrytill/hbt-go/internal"
+	"github.com/henrytill/hbt-go/internal/compression"
+	"github.com/henrytill/hbt-go/internal/formatter"
+	"github.com/henrytill/hbt-go/internal/query"
+	"github.com/henrytill/hbt-go/internal/types"
 )
 
 var (
@@ -29,8 +36,37 @@ type Config struct {
 	OutputFile   *string
 	Info         *bool
 	ListTags     *bool
+	TraceLabel   *string
+	Provenance   *bool
 	Mappings     *string
-	InputFile    string
+	HTMLFolders  *bool
+	OPMLAll      *bool
+	FeedTitle    *string
+	FeedLink     *string
+	FeedAuthor   *string
+	FeedPrivate  *bool
+	CSVSeparator *string
+	CSVColumns   *string
+	GraphCluster *string
+	ChildLinks   *bool
+	Where        *string
+	Contradict   *string
+	Inputs       []inputSpec
+}
+
+// inputSpec is an input file argument, with the format given for it, if
+// any.
+type inputSpec struct {
+	filename string
+	format   Format
+}
+
+// displayName names the input in messages.
+func (s inputSpec) displayName() string {
+	if s.filename == stdinName {
+		return "stdin"
+	}
+	return s.filename
 }
 
 func inputFormats() string {
@@ -52,8 +88,13 @@ func outputFormats() string {
 }
 
 func showUsage() {
-	fmt.Printf("Usage: %s [OPTIONS] FILE\n\n", os.Args[0])
+	fmt.Printf("Usage: %s [OPTIONS] FILE[:FORMAT]...\n\n", os.Args[0])
 	fmt.Println("Process bookmark files in various formats")
+	fmt.Println("\nEach FILE may be - to read from stdin, and may name its input format")
+	fmt.Println("after a colon. Several files are merged into one collection in order.")
+	fmt.Printf("\nRun %s diff -h to compare two sources, %s merge -h to merge two sources\n", os.Args[0], os.Args[0])
+	fmt.Printf("descended from a common base, or %s dedupe -h to find entities whose URLs\n", os.Args[0])
+	fmt.Println("differ only in form.")
 	fmt.Println("\nOptions:")
 	flag.PrintDefaults()
 }
@@ -65,14 +106,13 @@ func showVersion() {
 	}
 }
 
-func detectInputFormat(filename string) (Format, error) {
-	format, ok := internal.DetectInputFormat(filename)
-	if !ok {
-		ext := filepath.Ext(filename)
-		if ext == "" {
-			return Format{}, fmt.Errorf("cannot detect input format of %s: no file extension (use -f)", filename)
-		}
-		return Format{}, fmt.Errorf("no parser for extension: %s", ext)
+// stdinName is the input file argument that reads from stdin.
+const stdinName = "-"
+
+func detectInputFormat(spec inputSpec, input *bufio.Reader) (Format, error) {
+	format, err := internal.DetectInput(spec.filename, input)
+	if err != nil {
+		return Format{}, fmt.Errorf("cannot detect input format of %s: %v (use -f)", spec.displayName(), err)
 	}
 	return format, nil
 }
@@ -80,7 +120,7 @@ func detectInputFormat(filename string) (Format, error) {
 func detectOutputFormat(filename string) (Format, error) {
 	format, ok := internal.DetectOutputFormat(filename)
 	if !ok {
-		ext := filepath.Ext(filename)
+		ext := filepath.Ext(compression.TrimSuffix(filename))
 		if ext == "" {
 			return Format{}, fmt.Errorf("cannot detect output format of %s: no file extension (use -t)", filename)
 		}
@@ -89,14 +129,112 @@ func detectOutputFormat(filename string) (Format, error) {
 	return format, nil
 }
 
+// openInput opens the input named by spec, decompressing it if needed, and
+// identifies its format unless one was given. The returned function closes
+// the input.
+func openInput(spec inputSpec) (*bufio.Reader, Format, func(), error) {
+	inputFile := os.Stdin
+	if spec.filename != stdinName {
+		var err error
+		inputFile, err = os.Open(spec.filename)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, Format{}, nil, fmt.Errorf("Input file does not exist: %s", spec.filename)
+			}
+			return nil, Format{}, nil, fmt.Errorf("failed to open input file: %w", err)
+		}
+	}
+
+	closeInput := func() {
+		if inputFile != os.Stdin {
+			inputFile.Close()
+		}
+	}
+
+	input := bufio.NewReader(inputFile)
+
+	// Compressed input is recognized by its magic bytes, whatever its name.
+	if c := compression.Sniff(input); c != compression.None {
+		decompressed, err := compression.NewReader(c, input)
+		if err != nil {
+			closeInput()
+			return nil, Format{}, nil, fmt.Errorf("failed to decompress %s: %w", spec.displayName(), err)
+		}
+		closeFile := closeInput
+		closeInput = func() {
+			decompressed.Close()
+			closeFile()
+		}
+		input = bufio.NewReader(decompressed)
+	}
+
+	// If no input format was specified, detect it from the filename, or from
+	// the content where the filename is not conclusive
+	format := spec.format
+	if format.Name == "" {
+		var err error
+		format, err = detectInputFormat(spec, input)
+		if err != nil {
+			closeInput()
+			return nil, Format{}, nil, err
+		}
+	}
+
+	return input, format, closeInput, nil
+}
+
+// readInput parses the input named by spec into a collection.
+func readInput(spec inputSpec, opts internal.Options) (types.Collection, Format, error) {
+	input, format, closeInput, err := openInput(spec)
+	if err != nil {
+		return types.Collection{}, Format{}, err
+	}
+	defer closeInput()
+
+	coll, err := internal.Parse(format, input, opts)
+	if err != nil {
+		return types.Collection{}, Format{}, fmt.Errorf("failed to parse %s: %w", spec.displayName(), err)
+	}
+
+	return coll, format, nil
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "dedupe":
+			runDedupe(os.Args[2:])
+			return
+		}
+	}
+
 	config := Config{
 		InputFormat:  internal.NewInputFormatFlag(),
 		OutputFormat: internal.NewOutputFormatFlag(),
 		OutputFile:   flag.String("o", "", "Output file (defaults to stdout)"),
 		Info:         flag.Bool("info", false, "Show collection info (entity count)"),
 		ListTags:     flag.Bool("list-tags", false, "List all tags"),
+		TraceLabel:   flag.String("trace-label", "", "List the entities labeled LABEL and the inputs that gave each the label"),
+		Provenance:   flag.Bool("provenance", false, "Record the input each entity's values came from, in hbt output formats"),
 		Mappings:     flag.String("mappings", "", "Read mappings from FILE"),
+		HTMLFolders:  flag.Bool("html-folders", false, "Write HTML output into nested folders"),
+		OPMLAll:      flag.Bool("opml-all", false, "Write all entities to OPML output, not only feeds"),
+		FeedTitle:    flag.String("feed-title", "", "Title of Atom and RSS output (defaults to Bookmarks)"),
+		FeedLink:     flag.String("feed-link", "", "URL that Atom and RSS output is published at (required for RSS)"),
+		FeedAuthor:   flag.String("feed-author", "", "Author of Atom output (defaults to hbt)"),
+		FeedPrivate:  flag.Bool("feed-private", false, "Include entities not marked shared in Atom and RSS output"),
+		CSVSeparator: flag.String("csv-separator", "", "Separator of multi-valued CSV and TSV fields (defaults to a line break)"),
+		CSVColumns:   flag.String("csv-columns", "", "Read CSV and TSV column mappings from FILE"),
+		GraphCluster: flag.String("graph-cluster", "", "Group DOT and GraphML nodes by label or day"),
+		ChildLinks:   flag.Bool("child-links", false, "Read nested Markdown and Org list items as child-of links rather than edges"),
+		Contradict:   flag.String("contradictions", "false", "Write flags that sources disagree on as false, true, or unset, or refuse to write them"),
+		Where:        flag.String("where", "", "Keep only the entities matching a filter EXPR, such as 'label:go and toread'"),
 	}
 
 	var showVersionFlag bool
@@ -120,26 +258,32 @@ func main() {
 	}
 
 	args := flag.Args()
-	if len(args) != 1 {
-		if len(args) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: input file required\n\n")
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: exactly one input file required, got %d\n\n", len(args))
-		}
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: input file required\n\n")
 		showUsage()
 		os.Exit(1)
 	}
 
-	config.InputFile = args[0]
-
-	// If no input format was specified, detect it from the filename
-	if config.InputFormat.Format.Name == "" {
-		format, err := detectInputFormat(config.InputFile)
+	stdinCount := 0
+	for _, arg := range args {
+		filename, format, err := internal.ParseInputArg(arg)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", arg, err)
 			os.Exit(1)
 		}
-		config.InputFormat.Format = format
+		// -f gives the format of the inputs that do not name their own
+		if format.Name == "" {
+			format = config.InputFormat.Format
+		}
+		if filename == stdinName {
+			stdinCount++
+		}
+		config.Inputs = append(config.Inputs, inputSpec{filename: filename, format: format})
+	}
+
+	if stdinCount > 1 {
+		fmt.Fprintf(os.Stderr, "Error: stdin (-) can be read only once\n")
+		os.Exit(1)
 	}
 
 	// If no output format was specified, detect it from the output filename
@@ -152,39 +296,140 @@ func main() {
 		config.OutputFormat.Format = format
 	}
 
-	if !*config.Info && !*config.ListTags && config.OutputFormat.Format.Name == "" {
-		fmt.Fprintf(os.Stderr, "Error: Must specify an output format (-t) or analysis flag (--info, --list-tags)\n")
+	analysis := *config.Info || *config.ListTags || *config.TraceLabel != ""
+	if !analysis && config.OutputFormat.Format.Name == "" {
+		fmt.Fprintf(os.Stderr, "Error: Must specify an output format (-t) or analysis flag (--info, --list-tags, --trace-label)\n")
 		os.Exit(1)
 	}
 
-	inputFile, err := os.Open(config.InputFile)
-	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			fmt.Fprintf(os.Stderr, "Error: Input file does not exist: %s\n", config.InputFile)
-		} else {
-			fmt.Fprintf(os.Stderr, "Error opening input file: %v\n", err)
+	opts := internal.Options{
+		HTMLFolders:  *config.HTMLFolders,
+		OPMLAll:      *config.OPMLAll,
+		FeedTitle:    *config.FeedTitle,
+		FeedLink:     *config.FeedLink,
+		FeedAuthor:   *config.FeedAuthor,
+		FeedPrivate:  *config.FeedPrivate,
+		CSVSeparator: *config.CSVSeparator,
+		ChildLinks:   *config.ChildLinks,
+	}
+
+	if *config.CSVColumns != "" {
+		columns, err := internal.LoadColumns(*config.CSVColumns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading columns file: %v\n", err)
+			os.Exit(1)
 		}
+		opts.CSVColumns = columns
+	}
+
+	var err error
+	opts.GraphCluster, err = formatter.ParseGraphCluster(*config.GraphCluster)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer inputFile.Close()
 
-	coll, err := internal.Parse(config.InputFormat.Format, inputFile)
+	opts.Contradictions, err = types.ParseContradictionPolicy(*config.Contradict)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	var mappings map[string]string
 	if *config.Mappings != "" {
-		mappings, err := internal.LoadMappings(*config.Mappings)
+		mappings, err = internal.LoadMappings(*config.Mappings)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading mappings file: %v\n", err)
 			os.Exit(1)
 		}
+	}
+
+	var where query.Expr
+	if *config.Where != "" {
+		where, err = query.Parse(*config.Where)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Tracing a label needs to know where each label came from.
+	recordSources := *config.Provenance || *config.TraceLabel != ""
+
+	var coll types.Collection
+	for i, spec := range config.Inputs {
+		input, format, closeInput, err := openInput(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Conversions between streaming formats pass each node straight
+		// through, so collections larger than memory can be piped through
+		// hbt. Entities sharing a URL are not absorbed into one on this
+		// path.
+		if len(config.Inputs) == 1 && !analysis &&
+			internal.CanStream(format, config.OutputFormat.Format) {
+			transform := func(nodes iter.Seq2[types.Node, error]) iter.Seq2[types.Node, error] {
+				if recordSources {
+					nodes = recordNodes(nodes, spec.displayName(), format.Name)
+				}
+				if mappings != nil {
+					nodes = mapNodes(nodes, mappings)
+				}
+				if where != nil {
+					nodes = query.Filter(nodes, where)
+				}
+				return listNodeContradictions(nodes, config.OutputFormat.Format, opts.Contradictions)
+			}
+			streamConversion(format, input, config.OutputFormat.Format, *config.OutputFile, opts, transform)
+			closeInput()
+			return
+		}
+
+		parsed, err := internal.Parse(format, input, opts)
+		closeInput()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", spec.displayName(), err)
+			os.Exit(1)
+		}
+		if recordSources {
+			parsed.RecordSource(spec.displayName(), format.Name)
+		}
+
+		// The first collection is taken as is rather than copied. Records
+		// the parser absorbed, repeating one earlier in the same input,
+		// count as absorbed too.
+		added, absorbed := parsed.Len(), parsed.Absorbed()
+		if i == 0 {
+			coll = parsed
+		} else {
+			var merged int
+			added, merged = coll.Merge(&parsed)
+			absorbed += merged
+		}
+		if len(config.Inputs) > 1 {
+			fmt.Fprintf(os.Stderr, "%s (%s): %d new, %d absorbed\n", spec.displayName(), format, added, absorbed)
+		}
+	}
+
+	if mappings != nil {
 		coll.ApplyMappings(mappings)
 	}
 
+	// Filtering follows mappings, so queries match the mapped labels.
+	var result query.Result
+	if where != nil {
+		result = query.Evaluate(&coll, where)
+		coll = query.FilterCollection(&coll, where)
+	}
+
 	if *config.Info {
 		fmt.Printf("Collection contains %d entities\n", coll.Len())
+		if where != nil {
+			fmt.Printf("Query: %d match, %d do not, %d unknown, %d contradicted\n",
+				len(result.Matches), len(result.NotMatches), len(result.Unknown), len(result.Contradicted))
+		}
 		return
 	}
 
@@ -204,28 +449,165 @@ func main() {
 		return
 	}
 
+	if *config.TraceLabel != "" {
+		traceLabel(&coll, types.Label(*config.TraceLabel))
+		return
+	}
+
 	if config.OutputFormat.Format.Name != "" {
-		output := os.Stdout
-		if *config.OutputFile != "" {
-			output, err = os.Create(*config.OutputFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
-				os.Exit(1)
-			}
-		}
+		listContradictions(&coll, config.OutputFormat.Format, opts.Contradictions)
 
-		err = internal.Unparse(config.OutputFormat.Format, output, &coll)
+		output, closeOutput := createOutput(*config.OutputFile)
+
+		err = internal.Unparse(config.OutputFormat.Format, output, &coll, opts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Close errors on the output file mean data may not have reached
-		// disk; unlike the read side, they must not be ignored.
-		if output != os.Stdout {
-			if err := output.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error closing output file: %v\n", err)
-				os.Exit(1)
+		closeOutput()
+	}
+}
+
+// listContradictions lists on stderr the entities whose sources disagree on
+// a flag, and how the flags will be written.
+func listContradictions(coll *types.Collection, format Format, policy types.ContradictionPolicy) {
+	for entity := range coll.Entities() {
+		reportContradictions(entity, format, policy)
+	}
+}
+
+// listNodeContradictions lists, as listContradictions does, the contradicted
+// entities among nodes as they stream past.
+func listNodeContradictions(nodes iter.Seq2[types.Node, error], format Format, policy types.ContradictionPolicy) iter.Seq2[types.Node, error] {
+	return func(yield func(types.Node, error) bool) {
+		for node, err := range nodes {
+			if err == nil {
+				reportContradictions(node.Entity, format, policy)
+			}
+			if !yield(node, err) {
+				return
+			}
+		}
+	}
+}
+
+// reportContradictions writes the line listContradictions gives entity, if
+// its sources disagree on any flag. Native formats keep the contradiction,
+// so only other formats say how it is written.
+func reportContradictions(entity types.Entity, format Format, policy types.ContradictionPolicy) {
+	fields := entity.Contradictions()
+	switch {
+	case len(fields) == 0:
+	case format.Native() || policy == types.ContradictionRefuse:
+		fmt.Fprintf(os.Stderr, "contradicted: %s: %s\n", entity.URI, strings.Join(fields, ", "))
+	default:
+		fmt.Fprintf(os.Stderr, "contradicted: %s: %s, written as %s\n", entity.URI, strings.Join(fields, ", "), policy)
+	}
+}
+
+// createOutput opens the output file, or returns stdout when there is none,
+// along with a function that finishes writing it. Output to a file whose
+// name has a compression suffix is compressed.
+func createOutput(filename string) (io.Writer, func()) {
+	if filename == "" {
+		return os.Stdout, func() {}
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	c, _ := compression.FromFilename(filename)
+	compressor, err := compression.NewWriter(c, file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Close errors on the output file mean data may not have reached disk;
+	// unlike the read side, they must not be ignored.
+	return compressor, func() {
+		if err := compressor.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error compressing output: %v\n", err)
+			os.Exit(1)
+		}
+		if err := file.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing output file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// streamConversion converts input to output one node at a time, passing the
+// nodes through transform on the way.
+func streamConversion(inputFormat Format, input *bufio.Reader, outputFormat Format, outputFile string, opts internal.Options, transform func(iter.Seq2[types.Node, error]) iter.Seq2[types.Node, error]) {
+	nodes, err := internal.Stream(inputFormat, input, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
+		os.Exit(1)
+	}
+	nodes = transform(nodes)
+
+	output, closeOutput := createOutput(outputFile)
+	err = internal.UnparseStream(outputFormat, output, nodes, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+	closeOutput()
+}
+
+// recordNodes records each node as read from file in format, as
+// Collection.RecordSource does.
+func recordNodes(nodes iter.Seq2[types.Node, error], file, format string) iter.Seq2[types.Node, error] {
+	return func(yield func(types.Node, error) bool) {
+		for node, err := range nodes {
+			if err == nil {
+				node.Entity.RecordSource(types.Source{File: file, Format: format, Record: int(node.ID) + 1})
+			}
+			if !yield(node, err) {
+				return
+			}
+		}
+	}
+}
+
+// traceLabel lists the entities labeled label, each with the recorded
+// sources that gave it the label. A label that no source gave was
+// introduced by --mappings.
+func traceLabel(coll *types.Collection, label types.Label) {
+	found := false
+	for entity := range coll.Entities() {
+		if _, ok := entity.Labels[label]; !ok {
+			continue
+		}
+		found = true
+		fmt.Println(entity.URI)
+		sources := entity.LabelSources(label)
+		if len(sources) == 0 {
+			fmt.Println("  (no source; from --mappings)")
+		}
+		for _, src := range sources {
+			fmt.Printf("  %s (%s), record %d\n", src.File, src.Format, src.Record)
+		}
+	}
+	if !found {
+		fmt.Printf("No entities labeled %s\n", label)
+	}
+}
+
+// mapNodes applies label mappings to each node as it is yielded.
+func mapNodes(nodes iter.Seq2[types.Node, error], mappings map[string]string) iter.Seq2[types.Node, error] {
+	return func(yield func(types.Node, error) bool) {
+		for node, err := range nodes {
+			if err == nil {
+				node.Entity.ApplyMappings(mappings)
+			}
+			if !yield(node, err) {
+				return
 			}
 		}
 	}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/henrytill/hbt-go/internal"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// hbt merge exits with status 1 when conflicts were left unresolved, and 2
+// when the merge could not be done, as git merge-file does.
+const (
+	mergeExitConflict = 1
+	mergeExitTrouble  = 2
+)
+
+func showMergeUsage(flags *flag.FlagSet) {
+	fmt.Printf("Usage: %s merge --base BASE[:FORMAT] [OPTIONS] OURS[:FORMAT] THEIRS[:FORMAT]\n\n", os.Args[0])
+	fmt.Println("Merge two collections descended from a common base")
+	fmt.Println("\nChanges made on either side are kept, including deletions. Conflicting")
+	fmt.Println("changes are listed on stderr and resolved by --conflict: report leaves")
+	fmt.Println("them unresolved and exits with status 1, while ours and theirs pick a side.")
+	fmt.Println("\nOptions:")
+	flags.PrintDefaults()
+}
+
+func runMerge(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+
+	inputFormat := internal.NewInputFormatFlag()
+	outputFormat := internal.NewOutputFormatFlag()
+	fromUsage := fmt.Sprintf("Input format of sources that do not name theirs (%s)", inputFormats())
+	toUsage := fmt.Sprintf("Output format (%s)", outputFormats())
+	flags.Var(&inputFormat, "f", fromUsage)
+	flags.Var(&inputFormat, "from", fromUsage)
+	flags.Var(&outputFormat, "t", toUsage)
+	flags.Var(&outputFormat, "to", toUsage)
+	baseArg := flags.String("base", "", "Common ancestor of the two sources (required)")
+	outputFile := flags.String("o", "", "Output file (defaults to stdout)")
+	policyName := flags.String("conflict", "report", "Conflict policy (report, ours, theirs)")
+	contradict := flags.String("contradictions", "false", "Write flags that sources disagree on as false, true, or unset, or refuse to write them")
+	flags.Usage = func() { showMergeUsage(flags) }
+
+	_ = flags.Parse(args)
+
+	if *baseArg == "" || flags.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Error: a --base and exactly two sources required\n\n")
+		showMergeUsage(flags)
+		os.Exit(mergeExitTrouble)
+	}
+
+	policy, err := types.ParseConflictPolicy(*policyName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(mergeExitTrouble)
+	}
+
+	var opts internal.Options
+	opts.Contradictions, err = types.ParseContradictionPolicy(*contradict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(mergeExitTrouble)
+	}
+
+	if outputFormat.Format.Name == "" && *outputFile != "" {
+		format, err := detectOutputFormat(*outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(mergeExitTrouble)
+		}
+		outputFormat.Format = format
+	}
+	if outputFormat.Format.Name == "" {
+		fmt.Fprintf(os.Stderr, "Error: Must specify an output format (-t)\n")
+		os.Exit(mergeExitTrouble)
+	}
+
+	var colls [3]types.Collection
+	usedStdin := false
+	for i, arg := range []string{*baseArg, flags.Arg(0), flags.Arg(1)} {
+		filename, format, err := internal.ParseInputArg(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", arg, err)
+			os.Exit(mergeExitTrouble)
+		}
+		if format.Name == "" {
+			format = inputFormat.Format
+		}
+		if filename == stdinName {
+			if usedStdin {
+				fmt.Fprintf(os.Stderr, "Error: stdin (-) can be read only once\n")
+				os.Exit(mergeExitTrouble)
+			}
+			usedStdin = true
+		}
+
+		colls[i], _, err = readInput(inputSpec{filename: filename, format: format}, internal.Options{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(mergeExitTrouble)
+		}
+	}
+
+	merged, conflicts := types.MergeThreeWay(&colls[0], &colls[1], &colls[2], policy)
+
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "conflict: %s\n", c)
+	}
+
+	listContradictions(&merged, outputFormat.Format, opts.Contradictions)
+
+	output, closeOutput := createOutput(*outputFile)
+	if err := internal.Unparse(outputFormat.Format, output, &merged, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(mergeExitTrouble)
+	}
+	closeOutput()
+
+	if len(conflicts) > 0 && policy == types.ConflictReport {
+		os.Exit(mergeExitConflict)
+	}
+}
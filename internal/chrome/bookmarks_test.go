@@ -0,0 +1,64 @@
+package chrome
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeConversion(t *testing.T) {
+	tests := []struct {
+		webkit string
+		unix   time.Time
+	}{
+		{"11644473600000000", time.Unix(0, 0)},
+		{"13253673600000000", time.Date(2020, 12, 29, 0, 0, 0, 0, time.UTC)},
+		{"13253673600123456", time.Date(2020, 12, 29, 0, 0, 0, 123456000, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTime(tt.webkit); !got.Equal(tt.unix) {
+			t.Errorf("ParseTime(%s) = %v, want %v", tt.webkit, got, tt.unix)
+		}
+		if got := FormatTime(tt.unix); got != tt.webkit {
+			t.Errorf("FormatTime(%v) = %s, want %s", tt.unix, got, tt.webkit)
+		}
+	}
+
+	for _, unset := range []string{"", "0", "-5", "soon"} {
+		if got := ParseTime(unset); !got.IsZero() {
+			t.Errorf("ParseTime(%q) = %v, want zero time", unset, got)
+		}
+	}
+	if got := FormatTime(time.Time{}); got != "0" {
+		t.Errorf("FormatTime(zero) = %s, want 0", got)
+	}
+}
+
+func TestComputeChecksum(t *testing.T) {
+	makeBookmarks := func(name string) Bookmarks {
+		return Bookmarks{
+			Roots: Roots{
+				BookmarkBar: Node{ID: "1", Name: BookmarkBarName, Type: TypeFolder, Children: []Node{
+					{ID: "4", Name: name, Type: TypeURL, URL: "https://example.com/"},
+				}},
+				Other:  Node{ID: "2", Name: OtherName, Type: TypeFolder, Children: []Node{}},
+				Synced: Node{ID: "3", Name: SyncedName, Type: TypeFolder, Children: []Node{}},
+			},
+		}
+	}
+
+	a := makeBookmarks("Example")
+	b := makeBookmarks("Example")
+	c := makeBookmarks("Exämple")
+
+	sum := a.ComputeChecksum()
+	if len(sum) != 32 {
+		t.Fatalf("checksum %q is not a hex MD5", sum)
+	}
+	if b.ComputeChecksum() != sum {
+		t.Error("checksum is not deterministic")
+	}
+	if c.ComputeChecksum() == sum {
+		t.Error("checksum does not cover bookmark names")
+	}
+}
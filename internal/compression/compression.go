@@ -0,0 +1,118 @@
+// Package compression wraps readers and writers in the compressed formats
+// hbt reads and writes transparently: gzip, Zstandard, and xz. Compressed
+// input is recognized by its magic bytes, and compressed output by the
+// suffix of its filename.
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+type Compression struct {
+	Name   string
+	Suffix string
+	magic  string
+}
+
+var (
+	None = Compression{}
+	Gzip = Compression{"gzip", ".gz", "\x1f\x8b"}
+	Zstd = Compression{"zstd", ".zst", "\x28\xb5\x2f\xfd"}
+	XZ   = Compression{"xz", ".xz", "\xfd7zXZ\x00"}
+)
+
+var all = []Compression{Gzip, Zstd, XZ}
+
+func (c Compression) String() string {
+	if c == None {
+		return "none"
+	}
+	return c.Name
+}
+
+// FromFilename returns the compression named by the suffix of filename, and
+// filename without that suffix, so that the format of the content can be
+// detected from what remains.
+func FromFilename(filename string) (Compression, string) {
+	for _, c := range all {
+		if len(filename) > len(c.Suffix) && strings.EqualFold(filename[len(filename)-len(c.Suffix):], c.Suffix) {
+			return c, filename[:len(filename)-len(c.Suffix)]
+		}
+	}
+	return None, filename
+}
+
+// TrimSuffix returns filename without any compression suffix.
+func TrimSuffix(filename string) string {
+	_, trimmed := FromFilename(filename)
+	return trimmed
+}
+
+// Sniff identifies the compression of r by its magic bytes, peeking without
+// consuming them. Input that is not compressed is None.
+func Sniff(r *bufio.Reader) Compression {
+	for _, c := range all {
+		head, _ := r.Peek(len(c.magic))
+		if bytes.Equal(head, []byte(c.magic)) {
+			return c
+		}
+	}
+	return None
+}
+
+// NewReader returns a reader of the content of r, decompressed with c.
+// Closing it releases the decompressor but does not close r.
+func NewReader(c Compression, r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case XZ:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", c)
+	}
+}
+
+// NewWriter returns a writer that compresses with c what is written to it
+// and writes the result to w. Closing it flushes the compressor but does
+// not close w.
+func NewWriter(c Compression, w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	case XZ:
+		return xz.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", c)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,79 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	const content = "version: 0.1.0\nlength: 0\nvalue: []\n"
+
+	for _, c := range []Compression{None, Gzip, Zstd, XZ} {
+		t.Run(c.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(c, &buf)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			if _, err := io.WriteString(w, content); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			br := bufio.NewReader(&buf)
+			if got := Sniff(br); got != c {
+				t.Fatalf("Sniff = %v, want %v", got, c)
+			}
+
+			r, err := NewReader(c, br)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("round trip = %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestFromFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     Compression
+		trimmed  string
+	}{
+		{"bookmarks.html.gz", Gzip, "bookmarks.html"},
+		{"posts.json.ZST", Zstd, "posts.json"},
+		{"archive.yaml.xz", XZ, "archive.yaml"},
+		{"archive.yaml", None, "archive.yaml"},
+		{"Bookmarks.gz", Gzip, "Bookmarks"},
+		{".gz", None, ".gz"},
+	}
+
+	for _, tt := range tests {
+		got, trimmed := FromFilename(tt.filename)
+		if got != tt.want || trimmed != tt.trimmed {
+			t.Errorf("FromFilename(%q) = (%v, %q), want (%v, %q)",
+				tt.filename, got, trimmed, tt.want, tt.trimmed)
+		}
+	}
+}
+
+func TestSniffPlain(t *testing.T) {
+	for _, content := range []string{"", "\x1f", "<!DOCTYPE NETSCAPE-Bookmark-file-1>"} {
+		if got := Sniff(bufio.NewReader(strings.NewReader(content))); got != None {
+			t.Errorf("Sniff(%q) = %v, want none", content, got)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package delimited
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field names, as used for column headers and in column mappings. They match
+// the keys of the collection document.
+const (
+	FieldURI           = "uri"
+	FieldCreatedAt     = "createdAt"
+	FieldUpdatedAt     = "updatedAt"
+	FieldNames         = "names"
+	FieldLabels        = "labels"
+	FieldShared        = "shared"
+	FieldToRead        = "toRead"
+	FieldIsFeed        = "isFeed"
+	FieldExtended      = "extended"
+	FieldLastVisitedAt = "lastVisitedAt"
+)
+
+// Fields lists every field in the order the formatter writes them.
+var Fields = []string{
+	FieldURI,
+	FieldCreatedAt,
+	FieldUpdatedAt,
+	FieldNames,
+	FieldLabels,
+	FieldShared,
+	FieldToRead,
+	FieldIsFeed,
+	FieldExtended,
+	FieldLastVisitedAt,
+}
+
+// DefaultSeparator joins the values of a multi-valued field within a cell. A
+// line break, unlike punctuation, never occurs in names, labels, or times,
+// and spreadsheets show such a cell as a list. Extended notes, which may hold
+// line breaks, are not multi-valued.
+const DefaultSeparator = "\n"
+
+// Columns maps column headers to the fields they hold. Several columns may
+// map to one field: their values are combined for multi-valued fields and
+// extended notes, and the first non-empty value is used for the others. A
+// column mapped to the empty string is ignored.
+type Columns map[string]string
+
+// Validate reports a column mapped to an unknown field.
+func (c Columns) Validate() error {
+	for column, field := range c {
+		if field != "" && FieldOf(field) == "" {
+			return fmt.Errorf("column %q maps to unknown field %q", column, field)
+		}
+	}
+	return nil
+}
+
+// FieldOf returns the field named by name, compared case-insensitively, or
+// the empty string if there is none.
+func FieldOf(name string) string {
+	for _, field := range Fields {
+		if strings.EqualFold(field, strings.TrimSpace(name)) {
+			return field
+		}
+	}
+	return ""
+}
+
+// IsMultiValued reports whether field holds several values in one cell.
+func IsMultiValued(field string) bool {
+	switch field {
+	case FieldUpdatedAt, FieldNames, FieldLabels:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseTime parses a cell holding either Unix seconds or an RFC 3339 time.
+func ParseTime(s string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want Unix seconds or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// FormatTime formats a time as RFC 3339 in UTC.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ParseBool parses a cell holding true, false, yes, no, 1, or 0, in any
+// case.
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "1":
+		return true, nil
+	case "false", "no", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean %q", s)
+	}
+}
+
+// Split splits a multi-valued cell on sep, dropping empty values. An empty
+// sep leaves the cell whole.
+func Split(s, sep string) []string {
+	if sep == "" {
+		if s = strings.TrimSpace(s); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+	var values []string
+	for value := range strings.SplitSeq(s, sep) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -29,23 +29,46 @@ func TestFormatFlagSet(t *testing.T) {
 		}
 	})
 
-	t.Run("input flag rejects output-only format", func(t *testing.T) {
+	t.Run("input flag accepts yaml", func(t *testing.T) {
 		f := NewInputFormatFlag()
-		if err := f.Set("yaml"); err == nil {
-			t.Error("expected error setting input flag to yaml")
+		if err := f.Set("yaml"); err != nil {
+			t.Fatalf("Set(yaml): unexpected error: %v", err)
+		}
+		if f.Format != YAML {
+			t.Errorf("expected YAML, got %v", f.Format)
 		}
 	})
 
 	t.Run("output flag rejects input-only format", func(t *testing.T) {
 		f := NewOutputFormatFlag()
-		if err := f.Set("markdown"); err == nil {
-			t.Error("expected error setting output flag to markdown")
+		if err := f.Set("firefox"); err == nil {
+			t.Error("expected error setting output flag to firefox")
+		}
+	})
+
+	t.Run("input flag rejects output-only format", func(t *testing.T) {
+		f := NewInputFormatFlag()
+		if err := f.Set("atom"); err == nil {
+			t.Error("expected error setting input flag to atom")
+		}
+	})
+
+	t.Run("output flag accepts pinboard formats", func(t *testing.T) {
+		f := NewOutputFormatFlag()
+		if err := f.Set("json"); err != nil {
+			t.Fatalf("Set(json): unexpected error: %v", err)
+		}
+		if err := f.Set("xml"); err != nil {
+			t.Fatalf("Set(xml): unexpected error: %v", err)
+		}
+		if f.Format != XML {
+			t.Errorf("expected XML, got %v", f.Format)
 		}
 	})
 
 	t.Run("rejects unknown format", func(t *testing.T) {
 		f := NewInputFormatFlag()
-		if err := f.Set("csv"); err == nil {
+		if err := f.Set("docx"); err == nil {
 			t.Error("expected error for unknown format")
 		}
 	})
@@ -1,12 +1,19 @@
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"path/filepath"
 	"strings"
 
+	"github.com/henrytill/hbt-go/internal/compression"
+	"github.com/henrytill/hbt-go/internal/delimited"
 	"github.com/henrytill/hbt-go/internal/formatter"
+	pinboardformatter "github.com/henrytill/hbt-go/internal/formatter/pinboard"
 	"github.com/henrytill/hbt-go/internal/parser"
 	"github.com/henrytill/hbt-go/internal/parser/pinboard"
 	"github.com/henrytill/hbt-go/internal/types"
@@ -29,27 +36,165 @@ func (f Format) CanInput() bool  { return f.Capability&CapInput != 0 }
 func (f Format) CanOutput() bool { return f.Capability&CapOutput != 0 }
 func (f Format) String() string  { return f.Name }
 
+// Native reports whether f is one of hbt's own serializations, which hold
+// every part of a collection, contradicted flags included.
+func (f Format) Native() bool {
+	return f == YAML || f == HBTJSON || f == JSONL
+}
+
 var (
-	JSON     = Format{"json", CapInput}
-	XML      = Format{"xml", CapInput}
-	Markdown = Format{"markdown", CapInput}
+	JSON     = Format{"json", CapBoth}
+	XML      = Format{"xml", CapBoth}
+	Markdown = Format{"markdown", CapBoth}
 	HTML     = Format{"html", CapBoth}
-	YAML     = Format{"yaml", CapOutput}
+	YAML     = Format{"yaml", CapBoth}
+	HBTJSON  = Format{"hbt-json", CapBoth}
+	Chrome   = Format{"chrome", CapBoth}
+	Firefox  = Format{"firefox", CapInput}
+	OPML     = Format{"opml", CapBoth}
+	Atom     = Format{"atom", CapOutput}
+	RSS      = Format{"rss", CapOutput}
+	CSV      = Format{"csv", CapBoth}
+	TSV      = Format{"tsv", CapBoth}
+	Org      = Format{"org", CapBoth}
+	JSONL    = Format{"jsonl", CapBoth}
+	DOT      = Format{"dot", CapOutput}
+	GraphML  = Format{"graphml", CapOutput}
 )
 
-var parsers = map[Format]types.Parser{
-	JSON:     &pinboard.JSONParser{},
-	XML:      &pinboard.XMLParser{},
-	Markdown: &parser.MarkdownParser{},
-	HTML:     &parser.HTMLParser{},
+// Options configures the parsers and formatters that support it. Formats
+// ignore the options that do not apply to them.
+type Options struct {
+	// HTMLFolders writes HTML bookmarks into the folder hierarchy they were
+	// read from.
+	HTMLFolders bool
+	// OPMLAll writes every entity to OPML, not only feeds.
+	OPMLAll bool
+	// FeedTitle and FeedLink title and locate Atom and RSS feeds.
+	FeedTitle string
+	FeedLink  string
+	// FeedAuthor names the author of Atom feeds.
+	FeedAuthor string
+	// FeedPrivate includes entities not marked shared in Atom and RSS feeds.
+	FeedPrivate bool
+	// CSVSeparator joins the values of multi-valued CSV and TSV fields.
+	CSVSeparator string
+	// CSVColumns maps CSV and TSV column headers to entity fields.
+	CSVColumns delimited.Columns
+	// ChildLinks records the nesting of Markdown and Org list items as
+	// child-of links rather than edges.
+	ChildLinks bool
+	// GraphCluster groups the nodes of DOT and GraphML output.
+	GraphCluster formatter.GraphCluster
+	// Contradictions resolves, or refuses to write, flags that merged sources
+	// disagree on. It applies to every output format but the native ones,
+	// which write contradicted flags as they are.
+	Contradictions types.ContradictionPolicy
+}
+
+var parsers = map[Format]func(Options) types.Parser{
+	JSON: func(Options) types.Parser {
+		return &pinboard.JSONParser{}
+	},
+	XML: func(Options) types.Parser {
+		return &pinboard.XMLParser{}
+	},
+	Markdown: func(opts Options) types.Parser {
+		return &parser.MarkdownParser{ChildLinks: opts.ChildLinks}
+	},
+	HTML: func(Options) types.Parser {
+		return &parser.HTMLParser{}
+	},
+	YAML: func(Options) types.Parser {
+		return &parser.YAMLParser{}
+	},
+	HBTJSON: func(Options) types.Parser {
+		return &parser.JSONParser{}
+	},
+	Chrome: func(Options) types.Parser {
+		return &parser.ChromeParser{}
+	},
+	Firefox: func(Options) types.Parser {
+		return &parser.FirefoxParser{}
+	},
+	OPML: func(Options) types.Parser {
+		return &parser.OPMLParser{}
+	},
+	CSV: func(opts Options) types.Parser {
+		return &parser.CSVParser{Separator: opts.CSVSeparator, Columns: opts.CSVColumns}
+	},
+	TSV: func(opts Options) types.Parser {
+		return &parser.CSVParser{Comma: '\t', Separator: opts.CSVSeparator, Columns: opts.CSVColumns}
+	},
+	Org: func(opts Options) types.Parser {
+		return &parser.OrgParser{ChildLinks: opts.ChildLinks}
+	},
+	JSONL: func(Options) types.Parser {
+		return &parser.JSONLParser{}
+	},
 }
 
-var formatters = map[Format]types.Formatter{
-	HTML: &formatter.HTMLFormatter{},
-	YAML: &formatter.YAMLFormatter{},
+var formatters = map[Format]func(Options) types.Formatter{
+	JSON: func(Options) types.Formatter {
+		return &pinboardformatter.JSONFormatter{}
+	},
+	XML: func(Options) types.Formatter {
+		return &pinboardformatter.XMLFormatter{}
+	},
+	Markdown: func(Options) types.Formatter {
+		return &formatter.MarkdownFormatter{}
+	},
+	HTML: func(opts Options) types.Formatter {
+		return &formatter.HTMLFormatter{Folders: opts.HTMLFolders}
+	},
+	YAML: func(Options) types.Formatter {
+		return &formatter.YAMLFormatter{}
+	},
+	HBTJSON: func(Options) types.Formatter {
+		return &formatter.JSONFormatter{}
+	},
+	Chrome: func(Options) types.Formatter {
+		return &formatter.ChromeFormatter{}
+	},
+	OPML: func(opts Options) types.Formatter {
+		return &formatter.OPMLFormatter{All: opts.OPMLAll}
+	},
+	Atom: func(opts Options) types.Formatter {
+		return &formatter.AtomFormatter{
+			Title:   opts.FeedTitle,
+			Author:  opts.FeedAuthor,
+			Link:    opts.FeedLink,
+			Private: opts.FeedPrivate,
+		}
+	},
+	RSS: func(opts Options) types.Formatter {
+		return &formatter.RSSFormatter{
+			Title:   opts.FeedTitle,
+			Link:    opts.FeedLink,
+			Private: opts.FeedPrivate,
+		}
+	},
+	CSV: func(opts Options) types.Formatter {
+		return &formatter.CSVFormatter{Separator: opts.CSVSeparator}
+	},
+	TSV: func(opts Options) types.Formatter {
+		return &formatter.CSVFormatter{Comma: '\t', Separator: opts.CSVSeparator}
+	},
+	Org: func(Options) types.Formatter {
+		return &formatter.OrgFormatter{}
+	},
+	JSONL: func(Options) types.Formatter {
+		return &formatter.JSONLFormatter{}
+	},
+	DOT: func(opts Options) types.Formatter {
+		return &formatter.DOTFormatter{Cluster: opts.GraphCluster}
+	},
+	GraphML: func(opts Options) types.Formatter {
+		return &formatter.GraphMLFormatter{Cluster: opts.GraphCluster}
+	},
 }
 
-var allFormats = []Format{JSON, XML, Markdown, HTML, YAML}
+var allFormats = []Format{JSON, XML, Markdown, HTML, YAML, HBTJSON, Chrome, Firefox, OPML, Atom, RSS, CSV, TSV, Org, JSONL, DOT, GraphML}
 
 func AllInputFormats() []Format {
 	var result []Format
@@ -111,7 +256,38 @@ func (f *FormatFlag) Set(value string) error {
 	return nil
 }
 
+// ParseInputArg splits an input argument of the form FILE:FORMAT into the
+// filename and the input format it names. An argument whose text after the
+// last colon is not the name of a format is a filename alone, returned with
+// the zero Format, so filenames holding colons need no escaping.
+func ParseInputArg(arg string) (string, Format, error) {
+	i := strings.LastIndexByte(arg, ':')
+	if i <= 0 {
+		return arg, Format{}, nil
+	}
+
+	format, ok := parseFormat(arg[i+1:])
+	if !ok {
+		return arg, Format{}, nil
+	}
+	if !format.CanInput() {
+		return "", Format{}, fmt.Errorf("format %s cannot be used for input", format.Name)
+	}
+
+	return arg[:i], format, nil
+}
+
+// chromeBookmarksFile is the name of the bookmarks file in a Chromium
+// profile directory, which has no extension.
+const chromeBookmarksFile = "Bookmarks"
+
+// DetectInputFormat identifies the format of filename by its extension,
+// looking past a compression suffix such as .gz.
 func DetectInputFormat(filename string) (Format, bool) {
+	filename = compression.TrimSuffix(filename)
+	if filepath.Base(filename) == chromeBookmarksFile {
+		return Chrome, true
+	}
 	switch strings.ToLower(filepath.Ext(filename)) {
 	case ".html":
 		return HTML, true
@@ -121,44 +297,236 @@ func DetectInputFormat(filename string) (Format, bool) {
 		return XML, true
 	case ".md":
 		return Markdown, true
+	case ".yaml", ".yml":
+		return YAML, true
+	case ".sqlite":
+		return Firefox, true
+	case ".opml":
+		return OPML, true
+	case ".csv":
+		return CSV, true
+	case ".tsv":
+		return TSV, true
+	case ".org":
+		return Org, true
+	case ".jsonl":
+		return JSONL, true
 	default:
 		return Format{}, false
 	}
 }
 
+// DetectOutputFormat identifies the format of filename by its extension,
+// looking past a compression suffix such as .gz.
 func DetectOutputFormat(filename string) (Format, bool) {
+	filename = compression.TrimSuffix(filename)
+	if filepath.Base(filename) == chromeBookmarksFile {
+		return Chrome, true
+	}
 	switch strings.ToLower(filepath.Ext(filename)) {
 	case ".html":
 		return HTML, true
-	case ".yaml":
+	case ".md":
+		return Markdown, true
+	case ".yaml", ".yml":
 		return YAML, true
+	case ".json":
+		return HBTJSON, true
+	case ".xml":
+		return XML, true
+	case ".opml":
+		return OPML, true
+	case ".atom":
+		return Atom, true
+	case ".rss":
+		return RSS, true
+	case ".csv":
+		return CSV, true
+	case ".tsv":
+		return TSV, true
+	case ".org":
+		return Org, true
+	case ".jsonl":
+		return JSONL, true
+	case ".dot", ".gv":
+		return DOT, true
+	case ".graphml":
+		return GraphML, true
 	default:
 		return Format{}, false
 	}
 }
 
-func Parse(format Format, r io.Reader) (types.Collection, error) {
+// sniffLimit bounds how much input is peeked at to identify its format. It
+// fits the buffer of a default bufio.Reader.
+const sniffLimit = 4096
+
+// SniffJSONFormat tells the JSON formats apart by peeking at the start of r
+// without consuming it. A Pinboard export's top level is an array of
+// objects, so an array must open with an object or be empty; text such as a
+// Markdown or Org link that merely starts with a bracket is not JSON. An hbt
+// collection document, a JSONL collection, and a Chromium Bookmarks file all
+// start with an object, told apart by its keys: Chromium writes keys in
+// sorted order, starting with "checksum" or "roots"; an hbt document starts
+// with "version" followed by "length"; and a JSONL collection starts with a
+// line holding only "version", or with a node.
+func SniffJSONFormat(r *bufio.Reader) (Format, bool) {
+	head, _ := r.Peek(sniffLimit)
+
+	decoder := json.NewDecoder(bytes.NewReader(head))
+	token, err := decoder.Token()
+	if err != nil {
+		return Format{}, false
+	}
+	switch token {
+	case json.Delim('['):
+		if token, err := decoder.Token(); err == nil && (token == json.Delim('{') || token == json.Delim(']')) {
+			return JSON, true
+		}
+		return Format{}, false
+	case json.Delim('{'):
+	default:
+		return Format{}, false
+	}
+
+	token, err = decoder.Token()
+	if err != nil {
+		// An empty or truncated object is most likely a collection
+		// document; parsing it reports the real problem.
+		return HBTJSON, true
+	}
+	switch token {
+	case "checksum", "roots":
+		return Chrome, true
+	case "id", "entity", "edges":
+		return JSONL, true
+	case "version":
+		var version json.RawMessage
+		if err := decoder.Decode(&version); err != nil {
+			return HBTJSON, true
+		}
+		if token, err := decoder.Token(); err == nil && token == json.Delim('}') {
+			return JSONL, true
+		}
+		return HBTJSON, true
+	default:
+		return HBTJSON, true
+	}
+}
+
+func Parse(format Format, r io.Reader, opts Options) (types.Collection, error) {
 	if !format.CanInput() {
 		return types.Collection{}, fmt.Errorf("format %s cannot be used for input", format.Name)
 	}
 
-	parser, ok := parsers[format]
+	newParser, ok := parsers[format]
 	if !ok {
 		return types.Collection{}, fmt.Errorf("no parser available for format: %s", format.Name)
 	}
 
-	return parser.Parse(r)
+	return newParser(opts).Parse(r)
 }
 
-func Unparse(format Format, w io.Writer, coll *types.Collection) error {
+func Unparse(format Format, w io.Writer, coll *types.Collection, opts Options) error {
 	if !format.CanOutput() {
 		return fmt.Errorf("format %s cannot be used for output", format.Name)
 	}
 
-	formatter, ok := formatters[format]
+	newFormatter, ok := formatters[format]
 	if !ok {
 		return fmt.Errorf("no formatter available for format: %s", format.Name)
 	}
 
-	return formatter.Format(w, coll)
+	if !format.Native() && hasContradictions(coll) {
+		resolved, err := types.Collect(resolveContradictions(coll.Stream(), opts.Contradictions))
+		if err != nil {
+			return err
+		}
+		coll = &resolved
+	}
+
+	return newFormatter(opts).Format(w, coll)
+}
+
+func hasContradictions(coll *types.Collection) bool {
+	for entity := range coll.Entities() {
+		if len(entity.Contradictions()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveContradictions resolves the contradicted flags of each node by
+// policy, stopping with an error at the first node the policy refuses.
+func resolveContradictions(nodes iter.Seq2[types.Node, error], policy types.ContradictionPolicy) iter.Seq2[types.Node, error] {
+	return func(yield func(types.Node, error) bool) {
+		for node, err := range nodes {
+			if err == nil {
+				err = node.Entity.ResolveContradictions(policy)
+			}
+			if !yield(node, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// CanStream reports whether input can be converted to output one node at a
+// time, without building a collection in between.
+func CanStream(input, output Format) bool {
+	newParser, ok := parsers[input]
+	if !ok {
+		return false
+	}
+	newFormatter, ok := formatters[output]
+	if !ok {
+		return false
+	}
+	_, parserOK := newParser(Options{}).(types.StreamParser)
+	_, formatterOK := newFormatter(Options{}).(types.StreamFormatter)
+	return parserOK && formatterOK
+}
+
+// Stream returns an iterator over the nodes read from r, for formats whose
+// parser is a types.StreamParser.
+func Stream(format Format, r io.Reader, opts Options) (iter.Seq2[types.Node, error], error) {
+	if !format.CanInput() {
+		return nil, fmt.Errorf("format %s cannot be used for input", format.Name)
+	}
+
+	newParser, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("no parser available for format: %s", format.Name)
+	}
+
+	p, ok := newParser(opts).(types.StreamParser)
+	if !ok {
+		return nil, fmt.Errorf("format %s cannot be streamed", format.Name)
+	}
+
+	return p.Stream(r), nil
+}
+
+// UnparseStream writes nodes to w as they are yielded, for formats whose
+// formatter is a types.StreamFormatter.
+func UnparseStream(format Format, w io.Writer, nodes iter.Seq2[types.Node, error], opts Options) error {
+	if !format.CanOutput() {
+		return fmt.Errorf("format %s cannot be used for output", format.Name)
+	}
+
+	newFormatter, ok := formatters[format]
+	if !ok {
+		return fmt.Errorf("no formatter available for format: %s", format.Name)
+	}
+
+	f, ok := newFormatter(opts).(types.StreamFormatter)
+	if !ok {
+		return fmt.Errorf("format %s cannot be streamed", format.Name)
+	}
+
+	if !format.Native() {
+		nodes = resolveContradictions(nodes, opts.Contradictions)
+	}
+	return f.FormatStream(w, nodes)
 }
@@ -1,6 +1,15 @@
 package internal
 
-import "testing"
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
 
 func TestDetectInputFormat(t *testing.T) {
 	tests := []struct {
@@ -14,6 +23,22 @@ func TestDetectInputFormat(t *testing.T) {
 		{"posts.xml", XML, true},
 		{"notes.md", Markdown, true},
 		{"archive.tar.md", Markdown, true},
+		{"collection.yaml", YAML, true},
+		{"collection.yml", YAML, true},
+		{"Bookmarks", Chrome, true},
+		{"profile/Default/Bookmarks", Chrome, true},
+		{"bookmarks", Format{}, false},
+		{"places.sqlite", Firefox, true},
+		{"feeds.opml", OPML, true},
+		{"export.csv", CSV, true},
+		{"export.TSV", TSV, true},
+		{"links.org", Org, true},
+		{"archive.jsonl", JSONL, true},
+		{"bookmarks.html.gz", HTML, true},
+		{"posts.json.zst", JSON, true},
+		{"collection.yaml.xz", YAML, true},
+		{"Default/Bookmarks.gz", Chrome, true},
+		{"archive.gz", Format{}, false},
 		{"noextension", Format{}, false},
 		{"trailing.", Format{}, false},
 		{"file.txt", Format{}, false},
@@ -37,9 +62,27 @@ func TestDetectOutputFormat(t *testing.T) {
 	}{
 		{"out.html", HTML, true},
 		{"out.yaml", YAML, true},
+		{"out.yml", YAML, true},
+		{"out.md", Markdown, true},
+		{"out.xml", XML, true},
+		{"Default/Bookmarks", Chrome, true},
+		{"places.sqlite", Format{}, false},
+		{"feeds.opml", OPML, true},
+		{"recent.atom", Atom, true},
+		{"recent.rss", RSS, true},
+		{"out.csv", CSV, true},
+		{"out.tsv", TSV, true},
+		{"out.org", Org, true},
+		{"out.jsonl", JSONL, true},
+		{"archive.yaml.gz", YAML, true},
+		{"archive.jsonl.zst", JSONL, true},
+		{"archive.html.XZ", HTML, true},
+		{"notes.dot", DOT, true},
+		{"notes.gv", DOT, true},
+		{"notes.graphml", GraphML, true},
 		{"out.YAML", YAML, true},
 		{"noextension", Format{}, false},
-		{"out.json", Format{}, false},
+		{"out.json", HBTJSON, true},
 		{"", Format{}, false},
 	}
 
@@ -51,3 +94,130 @@ func TestDetectOutputFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestParseInputArg(t *testing.T) {
+	tests := []struct {
+		arg      string
+		filename string
+		want     Format
+	}{
+		{"bookmarks.html", "bookmarks.html", Format{}},
+		{"export:json", "export", JSON},
+		{"-:yaml", "-", YAML},
+		{"notes.txt:Markdown", "notes.txt", Markdown},
+		{"c:/data/bookmarks.html", "c:/data/bookmarks.html", Format{}},
+		{"2024-01-01T10:00.md", "2024-01-01T10:00.md", Format{}},
+		{":yaml", ":yaml", Format{}},
+	}
+
+	for _, tt := range tests {
+		filename, got, err := ParseInputArg(tt.arg)
+		if err != nil {
+			t.Errorf("ParseInputArg(%q): %v", tt.arg, err)
+			continue
+		}
+		if filename != tt.filename || got != tt.want {
+			t.Errorf("ParseInputArg(%q) = (%q, %v), want (%q, %v)",
+				tt.arg, filename, got, tt.filename, tt.want)
+		}
+	}
+
+	if _, _, err := ParseInputArg("feed.xml:atom"); err == nil {
+		t.Error("expected error for an output-only format")
+	}
+}
+
+func TestCanStream(t *testing.T) {
+	tests := []struct {
+		input, output Format
+		want          bool
+	}{
+		{JSONL, JSONL, true},
+		{JSONL, YAML, false},
+		{YAML, JSONL, false},
+		{JSONL, Format{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanStream(tt.input, tt.output); got != tt.want {
+			t.Errorf("CanStream(%v, %v) = %v, want %v", tt.input, tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestSniffJSONFormat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    Format
+		found   bool
+	}{
+		{`[{"href": "https://example.com/"}]`, JSON, true},
+		{"\n  \t[]", JSON, true},
+		{`{"version": "0.1.0", "length": 0, "value": []}`, HBTJSON, true},
+		{"\r\n{}", HBTJSON, true},
+		{`{"checksum": "abc", "roots": {}}`, Chrome, true},
+		{`{"roots": {}, "version": 1}`, Chrome, true},
+		{"{\"version\":\"0.1.0\"}\n{\"id\":0}\n", JSONL, true},
+		{`{"id":3,"entity":{},"edges":[]}`, JSONL, true},
+		{"{\n  \"version\": \"0.1.0\",\n  \"length\": 0\n}", HBTJSON, true},
+		{"", Format{}, false},
+		{"   ", Format{}, false},
+		{"null", Format{}, false},
+	}
+
+	for _, tt := range tests {
+		r := bufio.NewReader(strings.NewReader(tt.content))
+		got, found := SniffJSONFormat(r)
+		if got != tt.want || found != tt.found {
+			t.Errorf("SniffJSONFormat(%q) = (%v, %v), want (%v, %v)",
+				tt.content, got, found, tt.want, tt.found)
+		}
+		if rest, _ := r.Peek(len(tt.content)); string(rest) != tt.content {
+			t.Errorf("SniffJSONFormat(%q) consumed input, %q left", tt.content, rest)
+		}
+	}
+}
+
+func TestHBTJSONRoundTrip(t *testing.T) {
+	parent, err := url.Parse("https://example.com/parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := url.Parse("https://example.com/child")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coll := types.NewCollection()
+	parentID := coll.Upsert(types.Entity{
+		URI:       parent,
+		CreatedAt: types.CreatedAt(time.Unix(100, 0)),
+		Names:     map[types.Name]struct{}{"Parent": {}},
+		Labels:    map[types.Label]struct{}{"a": {}},
+		Shared:    types.NewShared(true),
+		Extended:  []types.Extended{"notes"},
+	})
+	childID := coll.Upsert(types.Entity{
+		URI:       child,
+		CreatedAt: types.CreatedAt(time.Unix(200, 0)),
+	})
+	coll.AddEdges(childID, parentID)
+
+	var first bytes.Buffer
+	if err := Unparse(HBTJSON, &first, &coll, Options{}); err != nil {
+		t.Fatalf("Unparse: %v", err)
+	}
+
+	got, err := Parse(HBTJSON, bytes.NewReader(first.Bytes()), Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var second bytes.Buffer
+	if err := Unparse(HBTJSON, &second, &got, Options{}); err != nil {
+		t.Fatalf("Unparse after round trip: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("round trip not stable:\nfirst:\n%s\nsecond:\n%s", first.String(), second.String())
+	}
+}
@@ -0,0 +1,117 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// AtomFormatter writes a collection as an Atom feed of its most recently
+// bookmarked entities. Each entry is titled by the entity's first name,
+// summarized by its first extended description, and categorized by its
+// labels; its id is the bookmarked URL. Only shared entities are written
+// unless Private is set.
+type AtomFormatter struct {
+	// Title titles the feed, defaulting to "Bookmarks".
+	Title string
+	// Author names the feed's author, which Atom requires, defaulting to
+	// "hbt".
+	Author string
+	// Link is the URL the feed is published at. It serves as the feed's id;
+	// without it, an id is derived from the title.
+	Link string
+	// Private includes entities that are not marked shared.
+	Private bool
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	ID         string         `xml:"id"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	Link    *atomLink   `xml:"link"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+func (f *AtomFormatter) Format(w io.Writer, coll *types.Collection) error {
+	entries := feedEntries(coll, f.Private)
+
+	title := f.Title
+	if title == "" {
+		title = defaultFeedTitle
+	}
+
+	author := f.Author
+	if author == "" {
+		author = defaultFeedAuthor
+	}
+
+	feed := atomFeed{
+		Title:   title,
+		ID:      f.Link,
+		Updated: feedUpdated(entries).Format(time.RFC3339),
+		Author:  atomAuthor{Name: author},
+		Entries: make([]atomEntry, 0, len(entries)),
+	}
+	if f.Link != "" {
+		feed.Link = &atomLink{Href: f.Link, Rel: "self"}
+	} else {
+		feed.ID = "urn:uuid:" + nameUUID("atom", title)
+	}
+
+	for _, e := range entries {
+		entry := atomEntry{
+			Title:     e.title,
+			Link:      atomLink{Href: e.href},
+			ID:        e.href,
+			Published: time.Time(e.entity.CreatedAt).UTC().Format(time.RFC3339),
+			Updated:   e.updated.Format(time.RFC3339),
+		}
+		if len(e.entity.Extended) > 0 {
+			entry.Summary = string(e.entity.Extended[0])
+		}
+		for _, label := range types.MapToSortedSlice(e.entity.Labels) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: label})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
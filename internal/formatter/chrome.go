@@ -0,0 +1,193 @@
+package formatter
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/chrome"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// ChromeFormatter writes a Chromium profile's Bookmarks file, checksum
+// included. Entities are placed by the folder paths recorded by the HTML and
+// Chrome parsers: a path starting with the bookmark bar or mobile root folder
+// is placed under that root, and any other path, or none, under the other
+// root. Labels that are not folder names have no place in the file and are
+// not written.
+type ChromeFormatter struct{}
+
+type chromeFolderBuilder struct {
+	folder     types.Folder
+	adopted    bool
+	items      []chromeItem
+	subfolders map[string]*chromeFolderBuilder
+}
+
+// chromeItem is either a bookmark or a subfolder.
+type chromeItem struct {
+	entity *types.Entity
+	folder *chromeFolderBuilder
+}
+
+func newChromeFolderBuilder(folder types.Folder) *chromeFolderBuilder {
+	return &chromeFolderBuilder{
+		folder:     folder,
+		subfolders: make(map[string]*chromeFolderBuilder),
+	}
+}
+
+// adopt takes a root folder's dates from its first occurrence in a path.
+func (b *chromeFolderBuilder) adopt(folder types.Folder) {
+	if !b.adopted {
+		b.folder = folder
+		b.adopted = true
+	}
+}
+
+func (b *chromeFolderBuilder) subfolder(folder types.Folder) *chromeFolderBuilder {
+	if sub, ok := b.subfolders[folder.Name]; ok {
+		return sub
+	}
+	sub := newChromeFolderBuilder(folder)
+	b.items = append(b.items, chromeItem{folder: sub})
+	b.subfolders[folder.Name] = sub
+	return sub
+}
+
+// nameUUID derives a stable identifier, laid out as a name-based UUID, from
+// the values that identify a node, so that formatting the same collection
+// twice gives the same file.
+func nameUUID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+type chromeEncoder struct {
+	nextID int
+}
+
+func (e *chromeEncoder) id() string {
+	id := strconv.Itoa(e.nextID)
+	e.nextID++
+	return id
+}
+
+func (e *chromeEncoder) bookmark(entity *types.Entity) chrome.Node {
+	var href string
+	if entity.URI != nil {
+		href = entity.URI.String()
+	}
+
+	name := href
+	if names := types.MapToSortedSlice(entity.Names); len(names) > 0 {
+		name = names[0]
+	}
+
+	var lastUsed time.Time
+	if t, ok := entity.LastVisitedAt.Get(); ok {
+		lastUsed = t
+	}
+
+	id := e.id()
+	return chrome.Node{
+		DateAdded:    chrome.FormatTime(time.Time(entity.CreatedAt)),
+		DateLastUsed: chrome.FormatTime(lastUsed),
+		GUID:         nameUUID(id, chrome.TypeURL, name, href),
+		ID:           id,
+		Name:         name,
+		Type:         chrome.TypeURL,
+		URL:          href,
+	}
+}
+
+// folder encodes b and its contents in pre-order. Roots take their fixed id
+// and GUID from the caller; other folders are numbered as they are reached.
+func (e *chromeEncoder) folder(b *chromeFolderBuilder, id, guid string) chrome.Node {
+	if id == "" {
+		id = e.id()
+		guid = nameUUID(id, chrome.TypeFolder, b.folder.Name)
+	}
+
+	children := make([]chrome.Node, 0, len(b.items))
+	for _, item := range b.items {
+		if item.folder != nil {
+			children = append(children, e.folder(item.folder, "", ""))
+		} else {
+			children = append(children, e.bookmark(item.entity))
+		}
+	}
+
+	return chrome.Node{
+		Children:     children,
+		DateAdded:    chrome.FormatTime(b.folder.AddDate),
+		DateLastUsed: chrome.FormatTime(time.Time{}),
+		DateModified: chrome.FormatTime(b.folder.LastModified),
+		GUID:         guid,
+		ID:           id,
+		Name:         b.folder.Name,
+		Type:         chrome.TypeFolder,
+	}
+}
+
+func (f *ChromeFormatter) Format(w io.Writer, coll *types.Collection) error {
+	bar := newChromeFolderBuilder(types.Folder{Name: chrome.BookmarkBarName})
+	other := newChromeFolderBuilder(types.Folder{Name: chrome.OtherName})
+	synced := newChromeFolderBuilder(types.Folder{Name: chrome.SyncedName})
+	roots := map[string]*chromeFolderBuilder{
+		chrome.BookmarkBarName: bar,
+		chrome.OtherName:       other,
+		chrome.SyncedName:      synced,
+	}
+
+	entities := make([]types.Entity, 0, coll.Len())
+	for entity := range coll.Entities() {
+		entities = append(entities, entity)
+	}
+
+	for i := range entities {
+		entity := &entities[i]
+
+		paths := entity.Folders
+		if len(paths) == 0 {
+			paths = []types.FolderPath{nil}
+		}
+
+		for _, path := range paths {
+			b := other
+			if len(path) > 0 {
+				if root, ok := roots[path[0].Name]; ok {
+					root.adopt(path[0])
+					b, path = root, path[1:]
+				}
+			}
+			for _, folder := range path {
+				b = b.subfolder(folder)
+			}
+			b.items = append(b.items, chromeItem{entity: entity})
+		}
+	}
+
+	e := &chromeEncoder{nextID: 4}
+	bookmarks := chrome.Bookmarks{
+		Roots: chrome.Roots{
+			BookmarkBar: e.folder(bar, "1", chrome.BookmarkBarGUID),
+			Other:       e.folder(other, "2", chrome.OtherGUID),
+			Synced:      e.folder(synced, "3", chrome.SyncedGUID),
+		},
+		Version: 1,
+	}
+	bookmarks.Checksum = bookmarks.ComputeChecksum()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "   ")
+
+	return encoder.Encode(&bookmarks)
+}
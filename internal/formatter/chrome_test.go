@@ -0,0 +1,85 @@
+package formatter
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/chrome"
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func TestChromeFormatterRoundTrip(t *testing.T) {
+	const input = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><H3 ADD_DATE="100" LAST_MODIFIED="200" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="300" LAST_VISIT="400">Go</A>
+        <DT><H3 ADD_DATE="100">Reading</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/nested" ADD_DATE="300">Nested</A>
+        </DL><p>
+    </DL><p>
+    <DT><A HREF="https://example.com/loose" ADD_DATE="300">Loose</A>
+</DL><p>
+`
+
+	html := &parser.HTMLParser{}
+	coll, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := (&ChromeFormatter{}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	out := buf.String()
+
+	var bookmarks chrome.Bookmarks
+	if err := json.Unmarshal([]byte(out), &bookmarks); err != nil {
+		t.Fatalf("output is not a Bookmarks file: %v\n%s", err, out)
+	}
+	if bookmarks.Checksum != bookmarks.ComputeChecksum() {
+		t.Errorf("checksum %s does not match contents", bookmarks.Checksum)
+	}
+
+	bar := bookmarks.Roots.BookmarkBar
+	if bar.ID != "1" || bar.GUID != chrome.BookmarkBarGUID || bar.DateAdded != chrome.FormatTime(time.Unix(100, 0)) {
+		t.Errorf("unexpected bookmark bar root: %+v", bar)
+	}
+	if len(bar.Children) != 2 || bar.Children[1].Type != chrome.TypeFolder || bar.Children[1].Name != "Reading" {
+		t.Fatalf("unexpected bookmark bar children: %+v", bar.Children)
+	}
+	if other := bookmarks.Roots.Other.Children; len(other) != 1 || other[0].URL != "https://example.com/loose" {
+		t.Errorf("unexpected other bookmarks: %+v", other)
+	}
+	if !strings.Contains(out, `"children": []`) {
+		t.Errorf("empty synced root must keep its children list:\n%s", out)
+	}
+
+	reparsed, err := (&parser.ChromeParser{}).Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("reparsing formatted output: %v", err)
+	}
+	original := slices.Collect(coll.Entities())
+	for i, got := range slices.Collect(reparsed.Entities()) {
+		if got.URI.String() != original[i].URI.String() || !got.LastVisitedAt.Equal(original[i].LastVisitedAt) {
+			t.Errorf("entity %d changed in round trip:\ngot:  %+v\nwant: %+v", i, got, original[i])
+		}
+		if !slices.EqualFunc(got.Folders, original[i].Folders, types.FolderPath.Equal) {
+			t.Errorf("entity %d folders: got %v, want %v", i, got.Folders, original[i].Folders)
+		}
+	}
+
+	var again strings.Builder
+	if err := (&ChromeFormatter{}).Format(&again, &reparsed); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if again.String() != out {
+		t.Errorf("formatting is not stable:\nfirst:\n%s\nsecond:\n%s", out, again.String())
+	}
+}
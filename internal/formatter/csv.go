@@ -0,0 +1,152 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/delimited"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// CSVFormatter writes delimited text with a header row naming the entity
+// fields, one row per entity, in the layout read by parser.CSVParser. Times
+// are written as RFC 3339 in UTC, and unset flags as empty cells. Each
+// extended note has a cell of its own, holding it as written, line breaks
+// included: the extended column is repeated as often as the entity with the
+// most notes needs. Edges, links, and folder paths have no column and are not
+// written.
+type CSVFormatter struct {
+	// Comma separates cells, defaulting to ','.
+	Comma rune
+	// Separator joins the values of multi-valued fields within a cell,
+	// defaulting to delimited.DefaultSeparator. A value containing it cannot
+	// be read back, and is reported as an error.
+	Separator string
+}
+
+func csvFlag(b bool, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return strconv.FormatBool(b)
+}
+
+func csvJoin(entity types.Entity, field string, values []string, separator string) (string, error) {
+	for _, value := range values {
+		if strings.Contains(value, separator) {
+			return "", fmt.Errorf("entity %s: %s value %q contains the separator %q",
+				entity.URI, field, value, separator)
+		}
+	}
+	return strings.Join(values, separator), nil
+}
+
+// csvHeader names the columns of the output, with notes extended columns.
+func csvHeader(notes int) []string {
+	header := make([]string, 0, len(delimited.Fields)+notes-1)
+	for _, field := range delimited.Fields {
+		if field == delimited.FieldExtended {
+			header = append(header, slices.Repeat([]string{field}, notes)...)
+		} else {
+			header = append(header, field)
+		}
+	}
+	return header
+}
+
+func csvRecord(entity types.Entity, separator string, notes int) ([]string, error) {
+	var href string
+	if entity.URI != nil {
+		href = entity.URI.String()
+	}
+
+	updatedAt := make([]string, len(entity.UpdatedAt))
+	for i, t := range entity.UpdatedAt {
+		updatedAt[i] = delimited.FormatTime(time.Time(t))
+	}
+
+	extended := make([]string, notes)
+	for i, e := range entity.Extended {
+		extended[i] = string(e)
+	}
+
+	var lastVisitedAt string
+	if t, ok := entity.LastVisitedAt.Get(); ok {
+		lastVisitedAt = delimited.FormatTime(t)
+	}
+
+	multi := map[string][]string{
+		delimited.FieldUpdatedAt: updatedAt,
+		delimited.FieldNames:     types.MapToSortedSlice(entity.Names),
+		delimited.FieldLabels:    types.MapToSortedSlice(entity.Labels),
+	}
+
+	record := make([]string, 0, len(delimited.Fields)+notes-1)
+	for _, field := range delimited.Fields {
+		var cell string
+		switch field {
+		case delimited.FieldURI:
+			cell = href
+		case delimited.FieldCreatedAt:
+			cell = delimited.FormatTime(time.Time(entity.CreatedAt))
+		case delimited.FieldShared:
+			cell = csvFlag(entity.Shared.Get())
+		case delimited.FieldToRead:
+			cell = csvFlag(entity.ToRead.Get())
+		case delimited.FieldIsFeed:
+			cell = csvFlag(entity.IsFeed.Get())
+		case delimited.FieldExtended:
+			record = append(record, extended...)
+			continue
+		case delimited.FieldLastVisitedAt:
+			cell = lastVisitedAt
+		default:
+			var err error
+			if cell, err = csvJoin(entity, field, multi[field], separator); err != nil {
+				return nil, err
+			}
+		}
+		record = append(record, cell)
+	}
+
+	return record, nil
+}
+
+func (f *CSVFormatter) Format(w io.Writer, coll *types.Collection) error {
+	separator := f.Separator
+	if separator == "" {
+		separator = delimited.DefaultSeparator
+	}
+
+	writer := csv.NewWriter(w)
+	if f.Comma != 0 {
+		writer.Comma = f.Comma
+	}
+
+	notes := 1
+	for entity := range coll.Entities() {
+		notes = max(notes, len(entity.Extended))
+	}
+
+	if err := writer.Write(csvHeader(notes)); err != nil {
+		return err
+	}
+
+	for entity := range coll.Entities() {
+		record, err := csvRecord(entity, separator, notes)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,146 @@
+package formatter
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+const csvYAMLInput = `version: v0.1.0
+length: 2
+value:
+  - id: 0
+    entity:
+      uri: https://go.dev/
+      createdAt: 1609200000
+      updatedAt: [1609286400, 1609372800]
+      names: [Go, The Go Programming Language]
+      labels: [go, lang]
+      shared: true
+      toRead: false
+      extended: ["Docs, tour, \"playground\""]
+      lastVisitedAt: 1609300000
+    edges: []
+  - id: 1
+    entity:
+      uri: https://example.com/
+      createdAt: 1609200000
+      updatedAt: []
+      names: []
+      labels: []
+    edges: []
+`
+
+func TestCSVFormatterRoundTrip(t *testing.T) {
+	for _, comma := range []rune{0, '\t'} {
+		coll, err := (&parser.YAMLParser{}).Parse(strings.NewReader(csvYAMLInput))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+
+		var buf strings.Builder
+		if err := (&CSVFormatter{Comma: comma}).Format(&buf, &coll); err != nil {
+			t.Fatalf("Format: %v", err)
+		}
+
+		got, err := (&parser.CSVParser{Comma: comma}).Parse(strings.NewReader(buf.String()))
+		if err != nil {
+			t.Fatalf("re-Parse: %v\n%s", err, buf.String())
+		}
+
+		want := slices.Collect(coll.Entities())
+		entities := slices.Collect(got.Entities())
+		if len(entities) != len(want) {
+			t.Fatalf("expected %d entities, got %d", len(want), len(entities))
+		}
+		for i := range want {
+			if !entities[i].Equal(want[i]) {
+				t.Errorf("comma %q: entity %d changed in round trip\ngot:  %+v\nwant: %+v",
+					comma, i, entities[i], want[i])
+			}
+		}
+	}
+}
+
+func TestCSVFormatterNotes(t *testing.T) {
+	const note = "First line\n    indented line\n\n  last line, with \"quotes\"\n"
+	coll := types.NewCollection()
+	coll.Upsert(types.Entity{
+		URI:       &url.URL{Scheme: "https", Host: "example.com", Path: "/"},
+		CreatedAt: types.CreatedAt(time.Unix(1609200000, 0)),
+		Names:     map[types.Name]struct{}{},
+		Labels:    map[types.Label]struct{}{"go": {}},
+		Extended:  []types.Extended{note, "second note"},
+	})
+	coll.Upsert(types.Entity{
+		URI:       &url.URL{Scheme: "https", Host: "example.com", Path: "/b"},
+		CreatedAt: types.CreatedAt(time.Unix(1609200000, 0)),
+		Names:     map[types.Name]struct{}{},
+		Labels:    map[types.Label]struct{}{},
+	})
+
+	for _, comma := range []rune{0, '\t'} {
+		var buf strings.Builder
+		if err := (&CSVFormatter{Comma: comma}).Format(&buf, &coll); err != nil {
+			t.Fatalf("comma %q: Format: %v", comma, err)
+		}
+		if header, _, _ := strings.Cut(buf.String(), "\n"); strings.Count(header, "extended") != 2 {
+			t.Errorf("comma %q: header = %q, want two extended columns", comma, header)
+		}
+
+		got, err := (&parser.CSVParser{Comma: comma}).Parse(strings.NewReader(buf.String()))
+		if err != nil {
+			t.Fatalf("comma %q: re-Parse: %v\n%s", comma, err, buf.String())
+		}
+		entities := slices.Collect(got.Entities())
+		if len(entities) != 2 {
+			t.Fatalf("comma %q: expected 2 entities, got %d", comma, len(entities))
+		}
+		if extended, want := entities[0].Extended, []types.Extended{note, "second note"}; !slices.Equal(extended, want) {
+			t.Errorf("comma %q: Extended = %q, want %q", comma, extended, want)
+		}
+		if extended := entities[1].Extended; len(extended) != 0 {
+			t.Errorf("comma %q: Extended = %q, want none", comma, extended)
+		}
+	}
+}
+
+func TestCSVFormatterHeader(t *testing.T) {
+	coll, err := (&parser.YAMLParser{}).Parse(strings.NewReader(csvYAMLInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := (&CSVFormatter{Separator: ";"}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	const header = "uri,createdAt,updatedAt,names,labels,shared,toRead,isFeed,extended,lastVisitedAt"
+	if lines[0] != header {
+		t.Errorf("header = %q, want %q", lines[0], header)
+	}
+	const row = `https://go.dev/,2020-12-29T00:00:00Z,2020-12-30T00:00:00Z;2020-12-31T00:00:00Z,` +
+		`Go;The Go Programming Language,go;lang,true,false,,"Docs, tour, ""playground""",2020-12-30T03:46:40Z`
+	if lines[1] != row {
+		t.Errorf("row = %q, want %q", lines[1], row)
+	}
+}
+
+func TestCSVFormatterSeparatorInValue(t *testing.T) {
+	coll, err := (&parser.YAMLParser{}).Parse(strings.NewReader(csvYAMLInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := (&CSVFormatter{Separator: " "}).Format(&buf, &coll); err == nil {
+		t.Error("expected error for a value containing the separator")
+	}
+}
@@ -0,0 +1,86 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// DOTFormatter writes the entity graph in the GraphViz DOT language. Each
+// entity is a node labeled by its first name, or its URL when it has none,
+// linking to its URL; each edge joined by AddEdges is an undirected edge.
+// A collection with links is written as a digraph, each link an arrow
+// labeled by its kind. A child-of link is ranked from parent to child, so
+// that hierarchies are drawn top down. With a Cluster, groups of nodes are
+// drawn in cluster subgraphs.
+type DOTFormatter struct {
+	Cluster GraphCluster
+}
+
+// dotEscaper escapes a string for a double-quoted DOT ID.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+func (f *DOTFormatter) Format(w io.Writer, coll *types.Collection) error {
+	g := newEntityGraph(coll, f.Cluster)
+
+	bw := bufio.NewWriter(w)
+
+	writeNode := func(i int, indent string) {
+		node := g.nodes[i]
+		fmt.Fprintf(bw, "%s%s [label=%s", indent, graphNodeID(i), dotQuote(node.label))
+		if node.href != "" {
+			fmt.Fprintf(bw, ", URL=%s, tooltip=%s", dotQuote(node.href), dotQuote(node.href))
+		}
+		bw.WriteString("];\n")
+	}
+
+	directed := len(g.links) > 0
+	if directed {
+		bw.WriteString("digraph hbt {\n")
+	} else {
+		bw.WriteString("graph hbt {\n")
+	}
+	bw.WriteString("  node [shape=box];\n")
+
+	for i, group := range g.groups {
+		fmt.Fprintf(bw, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(bw, "    label=%s;\n", dotQuote(group.title))
+		for _, node := range group.nodes {
+			writeNode(node, "    ")
+		}
+		bw.WriteString("  }\n")
+	}
+
+	for _, node := range g.ungrouped {
+		writeNode(node, "  ")
+	}
+
+	for _, edge := range g.edges {
+		if directed {
+			fmt.Fprintf(bw, "  %s -> %s [dir=none];\n", graphNodeID(edge[0]), graphNodeID(edge[1]))
+		} else {
+			fmt.Fprintf(bw, "  %s -- %s;\n", graphNodeID(edge[0]), graphNodeID(edge[1]))
+		}
+	}
+
+	for _, link := range g.links {
+		if link.kind == types.LinkChildOf {
+			fmt.Fprintf(bw, "  %s -> %s [label=%s, dir=back];\n",
+				graphNodeID(link.to), graphNodeID(link.from), dotQuote(string(link.kind)))
+		} else {
+			fmt.Fprintf(bw, "  %s -> %s [label=%s];\n",
+				graphNodeID(link.from), graphNodeID(link.to), dotQuote(string(link.kind)))
+		}
+	}
+
+	bw.WriteString("}\n")
+
+	return bw.Flush()
+}
@@ -0,0 +1,75 @@
+package formatter
+
+import (
+	"slices"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// defaultFeedTitle titles a feed when no title is given.
+const defaultFeedTitle = "Bookmarks"
+
+// defaultFeedAuthor names the author of an Atom feed when no author is given.
+const defaultFeedAuthor = "hbt"
+
+// feedEntry is an entity selected for a feed, with the time it was last
+// bookmarked or updated.
+type feedEntry struct {
+	entity  types.Entity
+	href    string
+	title   string
+	updated time.Time
+}
+
+// feedEntries selects the entities a feed publishes, newest first. Only
+// entities marked shared are selected unless private is set, so that links
+// whose visibility is unknown are not published by accident. Entities dated
+// the same keep their collection order.
+func feedEntries(coll *types.Collection, private bool) []feedEntry {
+	var entries []feedEntry
+	for entity := range coll.Entities() {
+		if shared, ok := entity.Shared.Get(); !private && !(ok && shared) {
+			continue
+		}
+
+		var href string
+		if entity.URI != nil {
+			href = entity.URI.String()
+		}
+
+		title := href
+		if names := types.MapToSortedSlice(entity.Names); len(names) > 0 {
+			title = names[0]
+		}
+
+		updated := time.Time(entity.CreatedAt)
+		for _, updatedAt := range entity.UpdatedAt {
+			if t := time.Time(updatedAt); t.After(updated) {
+				updated = t
+			}
+		}
+
+		entries = append(entries, feedEntry{
+			entity:  entity,
+			href:    href,
+			title:   title,
+			updated: updated.UTC(),
+		})
+	}
+
+	slices.SortStableFunc(entries, func(a, b feedEntry) int {
+		return b.updated.Compare(a.updated)
+	})
+
+	return entries
+}
+
+// feedUpdated is the time a feed was last updated: that of its newest entry,
+// or the Unix epoch for an empty feed, so that output stays reproducible.
+func feedUpdated(entries []feedEntry) time.Time {
+	if len(entries) == 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	return entries[0].updated
+}
@@ -0,0 +1,137 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+const feedHTMLInput = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><A HREF="https://example.com/old" ADD_DATE="1609200000" PRIVATE="0" TAGS="b,a">Old</A>
+    <DD>Old & worn
+    <DT><A HREF="https://example.com/secret" ADD_DATE="1609300000" PRIVATE="1">Secret</A>
+    <DT><A HREF="https://example.com/unknown" ADD_DATE="1609300000">Unknown</A>
+    <DT><A HREF="https://example.com/new" ADD_DATE="1609210000" LAST_MODIFIED="1609400000" PRIVATE="0">New</A>
+</DL><p>
+`
+
+func parseFeedInput(t *testing.T) types.Collection {
+	t.Helper()
+	html := &parser.HTMLParser{}
+	coll, err := html.Parse(strings.NewReader(feedHTMLInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return coll
+}
+
+func TestAtomFormatter(t *testing.T) {
+	coll := parseFeedInput(t)
+
+	var buf strings.Builder
+	f := &AtomFormatter{Title: "Recent", Author: "Example Author", Link: "https://example.com/recent.atom"}
+	if err := f.Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	const want = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Recent</title>
+  <link href="https://example.com/recent.atom" rel="self"></link>
+  <id>https://example.com/recent.atom</id>
+  <updated>2020-12-31T07:33:20Z</updated>
+  <author>
+    <name>Example Author</name>
+  </author>
+  <entry>
+    <title>New</title>
+    <link href="https://example.com/new"></link>
+    <id>https://example.com/new</id>
+    <published>2020-12-29T02:46:40Z</published>
+    <updated>2020-12-31T07:33:20Z</updated>
+  </entry>
+  <entry>
+    <title>Old</title>
+    <link href="https://example.com/old"></link>
+    <id>https://example.com/old</id>
+    <published>2020-12-29T00:00:00Z</published>
+    <updated>2020-12-29T00:00:00Z</updated>
+    <summary>Old &amp; worn</summary>
+    <category term="a"></category>
+    <category term="b"></category>
+  </entry>
+</feed>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAtomFormatterPrivate(t *testing.T) {
+	coll := parseFeedInput(t)
+
+	var buf strings.Builder
+	if err := (&AtomFormatter{Private: true}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	out := buf.String()
+
+	if n := strings.Count(out, "<entry>"); n != 4 {
+		t.Errorf("expected 4 entries with Private set, got %d", n)
+	}
+	if !strings.Contains(out, "<title>Bookmarks</title>") {
+		t.Error("expected default feed title")
+	}
+	if !strings.Contains(out, "<author>\n    <name>hbt</name>\n  </author>") {
+		t.Error("expected default feed author")
+	}
+	if !strings.Contains(out, "<id>urn:uuid:") {
+		t.Error("expected a derived feed id without a link")
+	}
+}
+
+func TestRSSFormatter(t *testing.T) {
+	coll := parseFeedInput(t)
+
+	var buf strings.Builder
+	if err := (&RSSFormatter{}).Format(&buf, &coll); err == nil {
+		t.Error("expected an error without a link")
+	}
+
+	buf.Reset()
+	if err := (&RSSFormatter{Link: "https://example.com/"}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	const want = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Bookmarks</title>
+    <link>https://example.com/</link>
+    <description>Bookmarks</description>
+    <lastBuildDate>Thu, 31 Dec 2020 07:33:20 +0000</lastBuildDate>
+    <item>
+      <title>New</title>
+      <link>https://example.com/new</link>
+      <guid isPermaLink="true">https://example.com/new</guid>
+      <pubDate>Thu, 31 Dec 2020 07:33:20 +0000</pubDate>
+    </item>
+    <item>
+      <title>Old</title>
+      <link>https://example.com/old</link>
+      <description>Old &amp; worn</description>
+      <category>a</category>
+      <category>b</category>
+      <guid isPermaLink="true">https://example.com/old</guid>
+      <pubDate>Tue, 29 Dec 2020 00:00:00 +0000</pubDate>
+    </item>
+  </channel>
+</rss>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
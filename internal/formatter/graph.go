@@ -0,0 +1,142 @@
+package formatter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// GraphCluster selects how DOTFormatter and GraphMLFormatter group the
+// nodes of the entity graph.
+type GraphCluster string
+
+const (
+	// ClusterNone leaves the nodes ungrouped.
+	ClusterNone GraphCluster = ""
+	// ClusterLabel groups nodes by their set of labels, as MarkdownFormatter
+	// groups them into sections. Nodes without labels are left ungrouped.
+	ClusterLabel GraphCluster = "label"
+	// ClusterDay groups nodes by the UTC day they were created.
+	ClusterDay GraphCluster = "day"
+)
+
+// ParseGraphCluster parses the name of a clustering, where "none" and the
+// empty string both mean ClusterNone.
+func ParseGraphCluster(s string) (GraphCluster, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return ClusterNone, nil
+	case string(ClusterLabel):
+		return ClusterLabel, nil
+	case string(ClusterDay):
+		return ClusterDay, nil
+	default:
+		return ClusterNone, fmt.Errorf("invalid graph clustering %q: expected none, label, or day", s)
+	}
+}
+
+type graphNode struct {
+	entity types.Entity
+	href   string
+	label  string
+}
+
+// graphGroup is a cluster of nodes, titled by the label set or day they
+// share.
+type graphGroup struct {
+	title string
+	nodes []int
+}
+
+// graphLink is a directed link between numbered nodes.
+type graphLink struct {
+	from, to int
+	kind     types.LinkKind
+}
+
+// entityGraph is a collection as a graph of numbered nodes. Each undirected
+// edge is listed once, however many times it was added, with its lower node
+// first. Links are listed in insertion order of their sources.
+type entityGraph struct {
+	nodes     []graphNode
+	edges     [][2]int
+	links     []graphLink
+	groups    []graphGroup
+	ungrouped []int
+}
+
+func newEntityGraph(coll *types.Collection, cluster GraphCluster) entityGraph {
+	var g entityGraph
+	position := make(map[types.Id]int)
+	ids := make([]types.Id, 0, coll.Len())
+
+	for id, entity := range coll.Nodes() {
+		var href string
+		if entity.URI != nil {
+			href = entity.URI.String()
+		}
+		label := href
+		if names := types.MapToSortedSlice(entity.Names); len(names) > 0 {
+			label = names[0]
+		}
+
+		position[id] = len(g.nodes)
+		ids = append(ids, id)
+		g.nodes = append(g.nodes, graphNode{entity: entity, href: href, label: label})
+	}
+
+	seen := make(map[[2]int]struct{})
+	for i, id := range ids {
+		for neighbor := range coll.Neighbors(id) {
+			edge := [2]int{i, position[neighbor]}
+			if edge[0] > edge[1] {
+				edge[0], edge[1] = edge[1], edge[0]
+			}
+			if _, ok := seen[edge]; ok {
+				continue
+			}
+			seen[edge] = struct{}{}
+			g.edges = append(g.edges, edge)
+		}
+		for target, kind := range coll.Links(id) {
+			g.links = append(g.links, graphLink{from: i, to: position[target], kind: kind})
+		}
+	}
+
+	groups := make(map[string]*graphGroup)
+	for i, node := range g.nodes {
+		var title string
+		switch cluster {
+		case ClusterLabel:
+			title = strings.Join(types.MapToSortedSlice(node.entity.Labels), ", ")
+		case ClusterDay:
+			title = utcDay(time.Time(node.entity.CreatedAt)).Format(time.DateOnly)
+		}
+		if title == "" {
+			g.ungrouped = append(g.ungrouped, i)
+			continue
+		}
+		group, ok := groups[title]
+		if !ok {
+			group = &graphGroup{title: title}
+			groups[title] = group
+		}
+		group.nodes = append(group.nodes, i)
+	}
+
+	for _, group := range groups {
+		g.groups = append(g.groups, *group)
+	}
+	slices.SortFunc(g.groups, func(a, b graphGroup) int {
+		return strings.Compare(a.title, b.title)
+	})
+
+	return g
+}
+
+func graphNodeID(i int) string {
+	return fmt.Sprintf("n%d", i)
+}
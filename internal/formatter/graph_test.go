@@ -0,0 +1,206 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+const graphYAMLInput = `version: v0.1.0
+length: 4
+value:
+  - id: 0
+    entity:
+      uri: https://go.dev/
+      createdAt: 1609200000
+      updatedAt: []
+      names: [Go]
+      labels: [go]
+    edges: [1, 1, 2]
+  - id: 1
+    entity:
+      uri: https://go.dev/tour/
+      createdAt: 1609200000
+      updatedAt: []
+      names: ['The "Tour"']
+      labels: [go]
+    edges: [0, 0]
+  - id: 2
+    entity:
+      uri: https://example.com/
+      createdAt: 1609286400
+      updatedAt: []
+      names: []
+      labels: []
+    edges: [0]
+  - id: 3
+    entity:
+      uri: https://example.com/other
+      createdAt: 1609286400
+      updatedAt: []
+      names: [Other]
+      labels: [misc]
+    edges: []
+`
+
+func parseGraphInput(t *testing.T) types.Collection {
+	t.Helper()
+	coll, err := (&parser.YAMLParser{}).Parse(strings.NewReader(graphYAMLInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return coll
+}
+
+func TestDOTFormatter(t *testing.T) {
+	coll := parseGraphInput(t)
+
+	var buf strings.Builder
+	if err := (&DOTFormatter{}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	const want = `graph hbt {
+  node [shape=box];
+  n0 [label="Go", URL="https://go.dev/", tooltip="https://go.dev/"];
+  n1 [label="The \"Tour\"", URL="https://go.dev/tour/", tooltip="https://go.dev/tour/"];
+  n2 [label="https://example.com/", URL="https://example.com/", tooltip="https://example.com/"];
+  n3 [label="Other", URL="https://example.com/other", tooltip="https://example.com/other"];
+  n0 -- n1;
+  n0 -- n2;
+}
+`
+	if buf.String() != want {
+		t.Errorf("output mismatch\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestDOTFormatterCluster(t *testing.T) {
+	coll := parseGraphInput(t)
+
+	tests := []struct {
+		cluster GraphCluster
+		want    []string
+	}{
+		{ClusterLabel, []string{
+			"  subgraph cluster_0 {\n    label=\"go\";\n    n0 ",
+			"  subgraph cluster_1 {\n    label=\"misc\";\n    n3 ",
+			"  }\n  n2 [",
+		}},
+		{ClusterDay, []string{
+			"  subgraph cluster_0 {\n    label=\"2020-12-29\";\n    n0 ",
+			"  subgraph cluster_1 {\n    label=\"2020-12-30\";\n    n2 ",
+		}},
+	}
+
+	for _, tt := range tests {
+		var buf strings.Builder
+		if err := (&DOTFormatter{Cluster: tt.cluster}).Format(&buf, &coll); err != nil {
+			t.Fatalf("Format: %v", err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("cluster %q: output missing %q\n%s", tt.cluster, want, buf.String())
+			}
+		}
+	}
+}
+
+func TestGraphMLFormatter(t *testing.T) {
+	coll := parseGraphInput(t)
+
+	var buf strings.Builder
+	if err := (&GraphMLFormatter{Cluster: ClusterLabel}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	var doc graphMLDocument
+	if err := xml.Unmarshal([]byte(buf.String()), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	root := doc.Graph
+	if root.EdgeDefault != "undirected" {
+		t.Errorf("edgedefault = %q, want undirected", root.EdgeDefault)
+	}
+	if len(root.Nodes) != 3 {
+		t.Fatalf("expected 2 groups and 1 ungrouped node, got %d nodes", len(root.Nodes))
+	}
+
+	group := root.Nodes[0]
+	if group.Graph == nil || len(group.Graph.Nodes) != 2 {
+		t.Fatalf("expected group holding 2 nodes, got %+v", group)
+	}
+	if group.Data[0].Value != "go" {
+		t.Errorf("group name = %q, want go", group.Data[0].Value)
+	}
+	if name := group.Graph.Nodes[1].Data[0].Value; name != `The "Tour"` {
+		t.Errorf("node name = %q", name)
+	}
+
+	wantEdges := []graphMLEdge{{Source: "n0", Target: "n1"}, {Source: "n0", Target: "n2"}}
+	if len(root.Edges) != len(wantEdges) {
+		t.Fatalf("edges = %v, want %v", root.Edges, wantEdges)
+	}
+	for i := range wantEdges {
+		if !reflect.DeepEqual(root.Edges[i], wantEdges[i]) {
+			t.Errorf("edge %d = %v, want %v", i, root.Edges[i], wantEdges[i])
+		}
+	}
+}
+
+func TestGraphLinks(t *testing.T) {
+	coll := parseGraphInput(t)
+	ids := make(map[string]types.Id)
+	for id, entity := range coll.Nodes() {
+		ids[entity.URI.String()] = id
+	}
+	coll.Link(ids["https://go.dev/tour/"], ids["https://go.dev/"], types.LinkChildOf)
+	coll.Link(ids["https://example.com/other"], ids["https://example.com/"], types.LinkCites)
+
+	var dot strings.Builder
+	if err := (&DOTFormatter{}).Format(&dot, &coll); err != nil {
+		t.Fatalf("DOT: %v", err)
+	}
+	for _, want := range []string{
+		"digraph hbt {\n",
+		"  n0 -> n1 [dir=none];\n",
+		"  n0 -> n1 [label=\"child-of\", dir=back];\n",
+		"  n3 -> n2 [label=\"cites\"];\n",
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("DOT output lacks %q:\n%s", want, dot.String())
+		}
+	}
+
+	var buf strings.Builder
+	if err := (&GraphMLFormatter{}).Format(&buf, &coll); err != nil {
+		t.Fatalf("GraphML: %v", err)
+	}
+	var doc graphMLDocument
+	if err := xml.Unmarshal([]byte(buf.String()), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Keys[len(doc.Keys)-1].ID != "kind" {
+		t.Errorf("keys = %v, want kind declared last", doc.Keys)
+	}
+	wantLink := graphMLEdge{Source: "n1", Target: "n0", Directed: "true", Data: []graphMLData{{Key: "kind", Value: "child-of"}}}
+	if got := doc.Graph.Edges[2]; !reflect.DeepEqual(got, wantLink) {
+		t.Errorf("first link = %+v, want %+v", got, wantLink)
+	}
+}
+
+func TestParseGraphCluster(t *testing.T) {
+	for _, s := range []string{"", "none", "Label", "day"} {
+		if _, err := ParseGraphCluster(s); err != nil {
+			t.Errorf("ParseGraphCluster(%q): %v", s, err)
+		}
+	}
+	if _, err := ParseGraphCluster("folder"); err == nil {
+		t.Error("expected error for unknown clustering")
+	}
+}
@@ -0,0 +1,144 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// GraphMLFormatter writes the entity graph as GraphML. Each entity is a node
+// carrying its name, URL, creation time, and labels as data; each edge
+// joined by AddEdges is an undirected edge, and each link a directed edge
+// carrying its kind as data. With a Cluster, each group of
+// nodes is a node of its own, titled by the group, holding the group's
+// nodes in a nested graph, which editors such as yEd draw as a group.
+type GraphMLFormatter struct {
+	Cluster GraphCluster
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID    string        `xml:"id,attr"`
+	Data  []graphMLData `xml:"data"`
+	Graph *graphMLGraph `xml:"graph"`
+}
+
+type graphMLEdge struct {
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr,omitempty"`
+	Data     []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{ID: "name", For: "node", AttrName: "name", AttrType: "string"},
+	{ID: "url", For: "node", AttrName: "url", AttrType: "string"},
+	{ID: "createdAt", For: "node", AttrName: "createdAt", AttrType: "string"},
+	{ID: "labels", For: "node", AttrName: "labels", AttrType: "string"},
+}
+
+// graphMLLinkKey is declared only for a graph with links.
+var graphMLLinkKey = graphMLKey{ID: "kind", For: "edge", AttrName: "kind", AttrType: "string"}
+
+func (f *GraphMLFormatter) Format(w io.Writer, coll *types.Collection) error {
+	g := newEntityGraph(coll, f.Cluster)
+
+	node := func(i int) graphMLNode {
+		n := g.nodes[i]
+		data := []graphMLData{
+			{Key: "name", Value: n.label},
+			{Key: "url", Value: n.href},
+			{Key: "createdAt", Value: time.Time(n.entity.CreatedAt).UTC().Format(time.RFC3339)},
+		}
+		if labels := types.MapToSortedSlice(n.entity.Labels); len(labels) > 0 {
+			data = append(data, graphMLData{Key: "labels", Value: strings.Join(labels, ", ")})
+		}
+		return graphMLNode{ID: graphNodeID(i), Data: data}
+	}
+
+	root := graphMLGraph{ID: "hbt", EdgeDefault: "undirected"}
+
+	for i, group := range g.groups {
+		id := fmt.Sprintf("c%d", i)
+		nested := &graphMLGraph{ID: id + ":", EdgeDefault: "undirected"}
+		for _, n := range group.nodes {
+			nested.Nodes = append(nested.Nodes, node(n))
+		}
+		root.Nodes = append(root.Nodes, graphMLNode{
+			ID:    id,
+			Data:  []graphMLData{{Key: "name", Value: group.title}},
+			Graph: nested,
+		})
+	}
+
+	for _, n := range g.ungrouped {
+		root.Nodes = append(root.Nodes, node(n))
+	}
+
+	// Every edge is declared in the root graph, which encloses both of its
+	// ends even when they are in different groups.
+	for _, edge := range g.edges {
+		root.Edges = append(root.Edges, graphMLEdge{
+			Source: graphNodeID(edge[0]),
+			Target: graphNodeID(edge[1]),
+		})
+	}
+
+	for _, link := range g.links {
+		root.Edges = append(root.Edges, graphMLEdge{
+			Source:   graphNodeID(link.from),
+			Target:   graphNodeID(link.to),
+			Directed: "true",
+			Data:     []graphMLData{{Key: "kind", Value: string(link.kind)}},
+		})
+	}
+
+	keys := graphMLKeys
+	if len(g.links) > 0 {
+		keys = append(slices.Clip(keys), graphMLLinkKey)
+	}
+
+	doc := graphMLDocument{Keys: keys, Graph: root}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/henrytill/hbt-go/internal/types"
 )
@@ -29,7 +30,14 @@ var (
 	)
 )
 
-type HTMLFormatter struct{}
+type HTMLFormatter struct {
+	// Folders rebuilds the folder hierarchy recorded by the HTML parser as
+	// nested <H3>/<DL> groups instead of writing one flat list. An entity
+	// filed under several folders is written once in each. The hierarchy
+	// survives a round trip through the hbt formats, which keep it from
+	// version 0.5.
+	Folders bool
+}
 
 // templateEntity holds the values interpolated into the bookmark template.
 // The string fields sourced from entity data (Href, Text, Tags, Extended)
@@ -115,43 +123,171 @@ func newTemplateEntity(entity types.Entity) templateEntity {
 	return ret
 }
 
+// templateFolder is a folder written as an <H3> heading followed by its
+// nested list. Name is HTML-escaped by folderBuilder.
+type templateFolder struct {
+	Name         string
+	AddDate      *int64
+	LastModified *int64
+	Items        []templateItem
+}
+
+// templateItem is either a bookmark or a folder, written at Indent.
+type templateItem struct {
+	Indent   string
+	Bookmark *templateEntity
+	Folder   *templateFolder
+}
+
+func unixOrNil(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+	unix := t.Unix()
+	return &unix
+}
+
+type folderBuilder struct {
+	folder     *templateFolder
+	indent     string
+	subfolders map[string]*folderBuilder
+}
+
+func newFolderBuilder(folder *templateFolder, indent string) *folderBuilder {
+	return &folderBuilder{
+		folder:     folder,
+		indent:     indent,
+		subfolders: make(map[string]*folderBuilder),
+	}
+}
+
+// subfolder returns the builder for the child folder named by folder,
+// creating it on first use. Dates are taken from the first occurrence.
+func (b *folderBuilder) subfolder(folder types.Folder) *folderBuilder {
+	if sub, ok := b.subfolders[folder.Name]; ok {
+		return sub
+	}
+	tf := &templateFolder{
+		Name:         textEscaper.Replace(folder.Name),
+		AddDate:      unixOrNil(folder.AddDate),
+		LastModified: unixOrNil(folder.LastModified),
+	}
+	b.folder.Items = append(b.folder.Items, templateItem{Indent: b.indent, Folder: tf})
+	sub := newFolderBuilder(tf, b.indent+"    ")
+	b.subfolders[folder.Name] = sub
+	return sub
+}
+
+func (b *folderBuilder) addBookmark(entity templateEntity) {
+	b.folder.Items = append(b.folder.Items, templateItem{Indent: b.indent, Bookmark: &entity})
+}
+
+// folderItems arranges the collection's entities into the folders they were
+// found under. Labels that merely repeat a folder name are left out of TAGS,
+// since the parser restores them from the hierarchy, and the description is
+// written only with the first occurrence so that re-reading the output does
+// not duplicate it.
+func folderItems(coll *types.Collection) []templateItem {
+	root := newFolderBuilder(&templateFolder{}, "    ")
+
+	for entity := range coll.Entities() {
+		folderNames := make(map[types.Label]struct{})
+		for _, path := range entity.Folders {
+			for _, folder := range path {
+				folderNames[types.Label(folder.Name)] = struct{}{}
+			}
+		}
+
+		var tags []string
+		for _, label := range types.MapToSortedSlice(entity.Labels) {
+			if _, ok := folderNames[types.Label(label)]; !ok {
+				tags = append(tags, label)
+			}
+		}
+
+		paths := entity.Folders
+		if len(paths) == 0 {
+			paths = []types.FolderPath{nil}
+		}
+
+		for i, path := range paths {
+			bookmark := newTemplateEntity(entity)
+			bookmark.Tags = attrEscaper.Replace(strings.Join(tags, ","))
+			if i > 0 {
+				bookmark.Extended = nil
+			}
+
+			b := root
+			for _, folder := range path {
+				b = b.subfolder(folder)
+			}
+			b.addBookmark(bookmark)
+		}
+	}
+
+	return root.folder.Items
+}
+
+func flatItems(coll *types.Collection) []templateItem {
+	items := make([]templateItem, 0, coll.Len())
+	for entity := range coll.Entities() {
+		bookmark := newTemplateEntity(entity)
+		items = append(items, templateItem{Indent: "    ", Bookmark: &bookmark})
+	}
+	return items
+}
+
+const itemsTemplate = `
+{{- range $item := .}}
+{{- if $item.Folder}}
+{{$item.Indent}}<DT><H3
+        {{- if $item.Folder.AddDate}} ADD_DATE="{{$item.Folder.AddDate}}"{{end}}
+        {{- if $item.Folder.LastModified}} LAST_MODIFIED="{{$item.Folder.LastModified}}"{{end}}>{{$item.Folder.Name}}</H3>
+{{$item.Indent}}<DL><p>
+{{- template "items" $item.Folder.Items}}
+{{$item.Indent}}</DL><p>
+{{- else}}{{$b := $item.Bookmark}}
+{{$item.Indent}}<DT><A HREF="{{$b.Href}}"
+        {{- if $b.AddDate}} ADD_DATE="{{$b.AddDate}}"{{end}}
+        {{- if $b.LastModified}} LAST_MODIFIED="{{$b.LastModified}}"{{end}}
+        {{- if $b.Tags}} TAGS="{{$b.Tags}}"{{end}}
+        {{- if $b.Private}} PRIVATE="{{$b.Private}}"{{end}}
+        {{- if $b.LastVisit}} LAST_VISIT="{{$b.LastVisit}}"{{end}}
+        {{- if $b.ToRead}} TOREAD="{{$b.ToRead}}"{{end}}
+        {{- if $b.Feed}} FEED="{{$b.Feed}}"{{end}}>{{$b.Text}}</A>
+{{- if $b.Extended}}
+{{$item.Indent}}<DD>{{$b.Extended}}
+{{- end}}
+{{- end}}
+{{- end}}`
+
 func (f *HTMLFormatter) Format(writer io.Writer, coll *types.Collection) error {
 	const tmpl = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
 <META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
 <TITLE>Bookmarks</TITLE>
 <H1>Bookmarks</H1>
 <DL><p>
-{{- range .Entities}}
-    <DT><A HREF="{{.Href}}"
-        {{- if .AddDate}} ADD_DATE="{{.AddDate}}"{{end}}
-        {{- if .LastModified}} LAST_MODIFIED="{{.LastModified}}"{{end}}
-        {{- if .Tags}} TAGS="{{.Tags}}"{{end}}
-        {{- if .Private}} PRIVATE="{{.Private}}"{{end}}
-        {{- if .LastVisit}} LAST_VISIT="{{.LastVisit}}"{{end}}
-        {{- if .ToRead}} TOREAD="{{.ToRead}}"{{end}}
-        {{- if .Feed}} FEED="{{.Feed}}"{{end}}>{{.Text}}</A>
-{{- if .Extended}}
-    <DD>{{.Extended}}
-{{- end}}
-{{- end}}
+{{- template "items" .Items}}
 </DL><p>
 `
 
 	templateData := struct {
-		Entities []templateEntity
-	}{
-		Entities: make([]templateEntity, 0, coll.Len()),
-	}
+		Items []templateItem
+	}{}
 
-	for entity := range coll.Entities() {
-		templateEntity := newTemplateEntity(entity)
-		templateData.Entities = append(templateData.Entities, templateEntity)
+	if f.Folders {
+		templateData.Items = folderItems(coll)
+	} else {
+		templateData.Items = flatItems(coll)
 	}
 
 	t, err := template.New("html").Parse(tmpl)
 	if err != nil {
 		return fmt.Errorf("failed to parse HTML template: %w", err)
 	}
+	if _, err := t.New("items").Parse(itemsTemplate); err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
 
 	return t.Execute(writer, templateData)
 }
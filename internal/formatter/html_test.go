@@ -126,3 +126,72 @@ func TestHTMLFormatterRoundTrip(t *testing.T) {
 		t.Errorf("extended: got %v, want %v", got.Extended, original.Extended)
 	}
 }
+
+func TestHTMLFormatterFolders(t *testing.T) {
+	const input = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><H3 ADD_DATE="100" LAST_MODIFIED="200">Outer</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/a" ADD_DATE="300" TAGS="go">A</A>
+        <DD>about a
+        <DT><H3>Inner &amp; more</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/b" ADD_DATE="300">B</A>
+        </DL><p>
+    </DL><p>
+    <DT><H3>Elsewhere</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/a" ADD_DATE="300" TAGS="go">A</A>
+    </DL><p>
+    <DT><A HREF="https://example.com/c" ADD_DATE="300">C</A>
+</DL><p>
+`
+
+	const want = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="100" LAST_MODIFIED="200">Outer</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/a" ADD_DATE="300" TAGS="go">A</A>
+        <DD>about a
+        <DT><H3>Inner &amp; more</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/b" ADD_DATE="300">B</A>
+        </DL><p>
+    </DL><p>
+    <DT><H3>Elsewhere</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/a" ADD_DATE="300" TAGS="go">A</A>
+    </DL><p>
+    <DT><A HREF="https://example.com/c" ADD_DATE="300">C</A>
+</DL><p>
+`
+
+	p := &parser.HTMLParser{}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf strings.Builder
+	f := &HTMLFormatter{Folders: true}
+	if err := f.Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	reparsed, err := p.Parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("reparsing formatted output: %v", err)
+	}
+	original := slices.Collect(coll.Entities())
+	for i, got := range slices.Collect(reparsed.Entities()) {
+		if !got.Equal(original[i]) {
+			t.Errorf("entity %d changed in round trip:\ngot:  %+v\nwant: %+v", i, got, original[i])
+		}
+	}
+}
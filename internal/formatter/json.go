@@ -0,0 +1,17 @@
+package formatter
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+type JSONFormatter struct{}
+
+func (f *JSONFormatter) Format(w io.Writer, coll *types.Collection) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(coll)
+}
@@ -0,0 +1,50 @@
+package formatter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"iter"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// JSONLFormatter writes a collection as JSON Lines: a line declaring the
+// version, then one line per node holding the node's id, entity, edges, and
+// links in the form they take in the hbt-json document. Each line stands
+// alone, so the output can be filtered and split with line-oriented tools and
+// read back with parser.JSONLParser.
+type JSONLFormatter struct{}
+
+func (f *JSONLFormatter) Format(w io.Writer, coll *types.Collection) error {
+	return formatJSONL(w, coll.Stream(), coll.Version())
+}
+
+// FormatStream declares ExpectedVersion, as the header is written before the
+// nodes that may call for it have been seen.
+func (f *JSONLFormatter) FormatStream(w io.Writer, nodes iter.Seq2[types.Node, error]) error {
+	return formatJSONL(w, nodes, types.ExpectedVersion)
+}
+
+func formatJSONL(w io.Writer, nodes iter.Seq2[types.Node, error], version types.Version) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+
+	header := struct {
+		Version string `json:"version"`
+	}{version.String()}
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+
+	for node, err := range nodes {
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(node); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,93 @@
+package formatter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func TestJSONLFormatterRoundTrip(t *testing.T) {
+	coll, err := (&parser.YAMLParser{}).Parse(strings.NewReader(csvYAMLInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := (&JSONLFormatter{}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != coll.Len()+1 {
+		t.Fatalf("expected a version line and %d node lines, got:\n%s", coll.Len(), buf.String())
+	}
+	if lines[0] != `{"version":"0.1.0"}` {
+		t.Errorf("version line = %s", lines[0])
+	}
+
+	got, err := (&parser.JSONLParser{}).Parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("re-Parse: %v\n%s", err, buf.String())
+	}
+
+	want := slices.Collect(coll.Entities())
+	entities := slices.Collect(got.Entities())
+	if len(entities) != len(want) {
+		t.Fatalf("expected %d entities, got %d", len(want), len(entities))
+	}
+	for i := range want {
+		if !entities[i].Equal(want[i]) {
+			t.Errorf("entity %d changed in round trip\ngot:  %+v\nwant: %+v", i, entities[i], want[i])
+		}
+	}
+}
+
+func TestJSONLFormatterStream(t *testing.T) {
+	const input = `{"version":"0.1.0"}
+{"id":0,"entity":{"uri":"https://example.com/a","createdAt":0,"updatedAt":[],"names":[],"labels":[]},"edges":[1]}
+{"id":1,"entity":{"uri":"https://example.com/b","createdAt":0,"updatedAt":[],"names":[],"labels":[]},"edges":[0]}
+`
+	var buf strings.Builder
+	nodes := (&parser.JSONLParser{}).Stream(strings.NewReader(input))
+	if err := (&JSONLFormatter{}).FormatStream(&buf, nodes); err != nil {
+		t.Fatalf("FormatStream: %v", err)
+	}
+	want := strings.Replace(input, "0.1.0", types.ExpectedVersion.String(), 1)
+	if buf.String() != want {
+		t.Errorf("stream output differs from input\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	nodes = (&parser.JSONLParser{}).Stream(strings.NewReader(input + "{\n"))
+	if err := (&JSONLFormatter{}).FormatStream(&buf, nodes); err == nil {
+		t.Error("expected the parser's error to be returned")
+	}
+}
+
+func TestJSONLFormatterStreamVersion(t *testing.T) {
+	// Only the second node has provenance, which version 0.1 cannot hold.
+	const input = `{"version":"0.2.0"}
+{"id":0,"entity":{"uri":"https://example.com/a","createdAt":0,"updatedAt":[],"names":[],"labels":[]}}
+{"id":1,"entity":{"uri":"https://example.com/b","createdAt":0,"updatedAt":[],"names":[],"labels":[],"provenance":[{"file":"b.json","createdAt":0}]}}
+`
+	var buf strings.Builder
+	nodes := (&parser.JSONLParser{}).Stream(strings.NewReader(input))
+	if err := (&JSONLFormatter{}).FormatStream(&buf, nodes); err != nil {
+		t.Fatalf("FormatStream: %v", err)
+	}
+
+	header, _, _ := strings.Cut(buf.String(), "\n")
+	if want := `{"version":"` + types.ExpectedVersion.String() + `"}`; header != want {
+		t.Errorf("version line = %s, want %s", header, want)
+	}
+
+	coll, err := (&parser.JSONLParser{}).Parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("re-Parse: %v\n%s", err, buf.String())
+	}
+	if !coll.HasProvenance() {
+		t.Errorf("provenance lost:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,235 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// markdownDateLayout is the layout of the date headings recognized by
+// parser.MarkdownParser.
+const markdownDateLayout = "January 2, 2006"
+
+// maxMarkdownLabels is the number of labels a single entity can carry: label
+// headings start at level 2, and Markdown has no headings deeper than 6.
+const maxMarkdownLabels = 5
+
+// MarkdownFormatter writes a collection in the structure read by
+// parser.MarkdownParser: entities are grouped under a date heading for the
+// day they were created, then under nested label headings, one level per
+// label in sorted order, and links and edges become nested list items.
+//
+// An entity is nested under its parent, the first entity it is linked to
+// as a child of in the same section, or without one under its earliest
+// inserted neighbor by an edge, which carries no direction. Only an entity
+// inserted before it qualifies, so that the nesting is a tree; links and
+// edges that do not fit the tree are not written. Each additional name, and
+// each later day in UpdatedAt, is written as a separate item so that the
+// parser absorbs it back into the same entity. Names and labels are written
+// verbatim, as the parser keeps backslash escapes in the text it extracts,
+// except that brackets in names are escaped so that they cannot end the link
+// text.
+type MarkdownFormatter struct{}
+
+type markdownSectionKey struct {
+	date   time.Time
+	labels string
+}
+
+type markdownSection struct {
+	date   time.Time
+	labels []string
+	items  []markdownItem
+}
+
+// markdownItem is one list item. A leaf item only restates a name or an
+// update day of its entity, so the entity's children are not nested under it.
+type markdownItem struct {
+	index int
+	name  string
+	leaf  bool
+}
+
+type markdownNode struct {
+	entity   types.Entity
+	date     time.Time
+	labels   []string
+	children []int
+}
+
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+var linkTextEscaper = strings.NewReplacer("[", `\[`, "]", `\]`)
+
+func markdownLink(name string, entity types.Entity) string {
+	var href string
+	if entity.URI != nil {
+		href = entity.URI.String()
+	}
+	if name == "" && entity.URI != nil && entity.URI.IsAbs() && !strings.ContainsAny(href, "<> ") {
+		return "<" + href + ">"
+	}
+	if strings.ContainsAny(href, "() ") {
+		href = "<" + href + ">"
+	}
+	return fmt.Sprintf("[%s](%s)", linkTextEscaper.Replace(name), href)
+}
+
+// treeParent returns the position of the entity the entity at position i
+// is nested under, as described for MarkdownFormatter, or -1 if it is not
+// nested.
+func treeParent(coll *types.Collection, ids []types.Id, position map[types.Id]int, i int, sameSection func(i, j int) bool) int {
+	for _, p := range coll.Parents(ids[i]) {
+		if j := position[p]; j < i && sameSection(i, j) {
+			return j
+		}
+	}
+
+	parent := -1
+	for neighbor := range coll.Neighbors(ids[i]) {
+		j := position[neighbor]
+		if j < i && (parent < 0 || j < parent) && sameSection(i, j) {
+			parent = j
+		}
+	}
+	return parent
+}
+
+func (f *MarkdownFormatter) Format(w io.Writer, coll *types.Collection) error {
+	var nodes []markdownNode
+	position := make(map[types.Id]int)
+	ids := make([]types.Id, 0, coll.Len())
+
+	for id, entity := range coll.Nodes() {
+		labels := types.MapToSortedSlice(entity.Labels)
+		if len(labels) > maxMarkdownLabels {
+			return fmt.Errorf("entity %s: %d labels exceed the %d Markdown can nest",
+				entity.URI, len(labels), maxMarkdownLabels)
+		}
+		position[id] = len(nodes)
+		ids = append(ids, id)
+		nodes = append(nodes, markdownNode{
+			entity: entity,
+			date:   utcDay(time.Time(entity.CreatedAt)),
+			labels: labels,
+		})
+	}
+
+	sameSection := func(i, j int) bool {
+		return nodes[i].date.Equal(nodes[j].date) && slices.Equal(nodes[i].labels, nodes[j].labels)
+	}
+
+	sections := make(map[markdownSectionKey]*markdownSection)
+	section := func(date time.Time, labels []string) *markdownSection {
+		key := markdownSectionKey{date: date, labels: strings.Join(labels, "\x00")}
+		sec, ok := sections[key]
+		if !ok {
+			sec = &markdownSection{date: date, labels: labels}
+			sections[key] = sec
+		}
+		return sec
+	}
+
+	for i := range nodes {
+		parent := treeParent(coll, ids, position, i, sameSection)
+
+		node := &nodes[i]
+		names := types.MapToSortedSlice(node.entity.Names)
+		var name string
+		if len(names) > 0 {
+			name = names[0]
+		}
+
+		sec := section(node.date, node.labels)
+		if parent < 0 {
+			sec.items = append(sec.items, markdownItem{index: i, name: name})
+		} else {
+			nodes[parent].children = append(nodes[parent].children, i)
+		}
+
+		if len(names) > 1 {
+			for _, extra := range names[1:] {
+				sec.items = append(sec.items, markdownItem{index: i, name: extra, leaf: true})
+			}
+		}
+
+		for _, updatedAt := range node.entity.UpdatedAt {
+			day := utcDay(time.Time(updatedAt))
+			if !day.After(node.date) {
+				continue
+			}
+			later := section(day, node.labels)
+			item := markdownItem{index: i, name: name, leaf: true}
+			if !slices.Contains(later.items, item) {
+				later.items = append(later.items, item)
+			}
+		}
+	}
+
+	ordered := make([]*markdownSection, 0, len(sections))
+	for _, sec := range sections {
+		ordered = append(ordered, sec)
+	}
+	slices.SortFunc(ordered, func(a, b *markdownSection) int {
+		if c := a.date.Compare(b.date); c != 0 {
+			return c
+		}
+		return slices.Compare(a.labels, b.labels)
+	})
+
+	bw := bufio.NewWriter(w)
+
+	var writeItem func(item markdownItem, depth int)
+	writeItem = func(item markdownItem, depth int) {
+		node := nodes[item.index]
+		fmt.Fprintf(bw, "%s- %s\n", strings.Repeat("  ", depth), markdownLink(item.name, node.entity))
+		if item.leaf {
+			return
+		}
+		for _, child := range node.children {
+			names := types.MapToSortedSlice(nodes[child].entity.Names)
+			var name string
+			if len(names) > 0 {
+				name = names[0]
+			}
+			writeItem(markdownItem{index: child, name: name}, depth+1)
+		}
+	}
+
+	var prev *markdownSection
+	for _, sec := range ordered {
+		var prevLabels []string
+		if prev == nil || !sec.date.Equal(prev.date) {
+			if !sec.date.IsZero() {
+				fmt.Fprintf(bw, "# %s\n\n", sec.date.Format(markdownDateLayout))
+			}
+		} else {
+			prevLabels = prev.labels
+		}
+
+		common := 0
+		for common < len(prevLabels) && common < len(sec.labels) && prevLabels[common] == sec.labels[common] {
+			common++
+		}
+		for level := common; level < len(sec.labels); level++ {
+			fmt.Fprintf(bw, "%s %s\n\n", strings.Repeat("#", level+2), sec.labels[level])
+		}
+
+		for _, item := range sec.items {
+			writeItem(item, 0)
+		}
+		bw.WriteString("\n")
+
+		prev = sec
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,172 @@
+package formatter
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func formatMarkdown(t *testing.T, coll *types.Collection) string {
+	t.Helper()
+	var buf strings.Builder
+	f := &MarkdownFormatter{}
+	if err := f.Format(&buf, coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	return buf.String()
+}
+
+func parseMarkdown(t *testing.T, input string) types.Collection {
+	t.Helper()
+	p := &parser.MarkdownParser{}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return coll
+}
+
+func TestMarkdownFormatterRoundTrip(t *testing.T) {
+	const input = `# November 15, 2023
+
+- [Undated child-free](https://example.com/plain)
+
+## Programming
+
+### Go
+
+- [Go](https://go.dev/)
+  - [Tour](https://go.dev/tour/)
+    - <https://go.dev/tour/welcome/1>
+  - [Spec](https://go.dev/ref/spec)
+
+## Reading
+
+- [A \*starred\* name](https://example.com/read)
+
+# November 16, 2023
+
+## Programming
+
+### Go
+
+- [Go, again](https://go.dev/)
+`
+
+	// Labels are a set, so their headings nest in sorted order rather than
+	// the order of the input.
+	const want = `# November 15, 2023
+
+- [Undated child-free](https://example.com/plain)
+
+## Go
+
+### Programming
+
+- [Go](https://go.dev/)
+  - [Tour](https://go.dev/tour/)
+    - <https://go.dev/tour/welcome/1>
+  - [Spec](https://go.dev/ref/spec)
+- [Go, again](https://go.dev/)
+
+## Reading
+
+- [A \*starred\* name](https://example.com/read)
+
+# November 16, 2023
+
+## Go
+
+### Programming
+
+- [Go](https://go.dev/)
+
+`
+
+	coll := parseMarkdown(t, input)
+	first := formatMarkdown(t, &coll)
+	if first != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", first, want)
+	}
+
+	reparsed := parseMarkdown(t, first)
+	second := formatMarkdown(t, &reparsed)
+
+	if second != first {
+		t.Errorf("parse→format→parse not stable:\nfirst:\n%s\nsecond:\n%s", first, second)
+	}
+	if reparsed.Len() != coll.Len() {
+		t.Fatalf("entity count changed: got %d, want %d", reparsed.Len(), coll.Len())
+	}
+
+	originals := make(map[string]types.Entity)
+	for e := range coll.Entities() {
+		originals[e.URI.String()] = e
+	}
+	for e := range reparsed.Entities() {
+		orig, ok := originals[e.URI.String()]
+		if !ok {
+			t.Errorf("unexpected entity %s after round trip", e.URI)
+			continue
+		}
+		if !e.Equal(orig) {
+			t.Errorf("entity %s changed in round trip:\ngot:  %+v\nwant: %+v", e.URI, e, orig)
+		}
+	}
+}
+
+func TestMarkdownFormatterBrackets(t *testing.T) {
+	const input = "# November 15, 2023\n\n- [\\[PDF\\] a\\]b](https://example.com/paper)\n"
+
+	coll := parseMarkdown(t, input)
+	names := types.MapToSortedSlice(slices.Collect(coll.Entities())[0].Names)
+	if want := []string{"[PDF] a]b"}; !slices.Equal(names, want) {
+		t.Fatalf("Names = %q, want %q", names, want)
+	}
+
+	output := formatMarkdown(t, &coll)
+	if !strings.Contains(output, input) {
+		t.Errorf("brackets not escaped:\n%s", output)
+	}
+	reparsed := parseMarkdown(t, output)
+	if got := types.MapToSortedSlice(slices.Collect(reparsed.Entities())[0].Names); !slices.Equal(got, names) {
+		t.Errorf("Names after round trip = %q, want %q", got, names)
+	}
+}
+
+func TestMarkdownFormatterUndated(t *testing.T) {
+	coll := parseMarkdown(t, "- [No date](https://example.com/)\n\n# January 2, 2006\n\n- <https://example.com/dated>\n")
+
+	got := formatMarkdown(t, &coll)
+	want := "- [No date](https://example.com/)\n\n# January 2, 2006\n\n- <https://example.com/dated>\n\n"
+	if got != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarkdownFormatterTooManyLabels(t *testing.T) {
+	u, err := url.Parse("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := make(map[types.Label]struct{})
+	for _, l := range []string{"a", "b", "c", "d", "e", "f"} {
+		labels[types.Label(l)] = struct{}{}
+	}
+	coll := types.NewCollection()
+	coll.Upsert(types.Entity{
+		URI:       u,
+		CreatedAt: types.CreatedAt(time.Unix(0, 0)),
+		Labels:    labels,
+	})
+
+	f := &MarkdownFormatter{}
+	if err := f.Format(&strings.Builder{}, &coll); err == nil {
+		t.Error("expected error for more labels than heading levels")
+	}
+}
@@ -0,0 +1,181 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/opml"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// OPMLFormatter writes a subscription list for feed readers. Only entities
+// with IsFeed set are written, as "rss" outlines, unless All is set, in which
+// case other entities are written as "link" outlines. Entities are nested
+// under the folder paths recorded by the browser parsers, and labels that are
+// not folder names are written as categories.
+type OPMLFormatter struct {
+	All bool
+}
+
+// opmlFolder is a folder outline under construction. Its children are
+// collected separately and attached once the tree is complete, since
+// appending to a slice of outlines would move the outlines already built.
+type opmlFolder struct {
+	outline  opml.Outline
+	children []opmlNode
+}
+
+// opmlNode is either an entity outline or a folder.
+type opmlNode struct {
+	outline *opml.Outline
+	folder  *opmlFolder
+}
+
+type opmlTree struct {
+	nodes      []opmlNode
+	subfolders map[string]*opmlTree
+	folder     *opmlFolder
+}
+
+func newOPMLTree(folder *opmlFolder) *opmlTree {
+	return &opmlTree{
+		subfolders: make(map[string]*opmlTree),
+		folder:     folder,
+	}
+}
+
+func (t *opmlTree) add(node opmlNode) {
+	if t.folder != nil {
+		t.folder.children = append(t.folder.children, node)
+	} else {
+		t.nodes = append(t.nodes, node)
+	}
+}
+
+func (t *opmlTree) subfolder(folder types.Folder) *opmlTree {
+	if sub, ok := t.subfolders[folder.Name]; ok {
+		return sub
+	}
+	f := &opmlFolder{outline: opml.Outline{
+		Text:    folder.Name,
+		Title:   folder.Name,
+		Created: opml.FormatTime(folder.AddDate),
+	}}
+	t.add(opmlNode{folder: f})
+	sub := newOPMLTree(f)
+	t.subfolders[folder.Name] = sub
+	return sub
+}
+
+func buildOPMLOutlines(nodes []opmlNode) []opml.Outline {
+	outlines := make([]opml.Outline, 0, len(nodes))
+	for _, node := range nodes {
+		if node.folder != nil {
+			outline := node.folder.outline
+			outline.Outlines = buildOPMLOutlines(node.folder.children)
+			outlines = append(outlines, outline)
+		} else {
+			outlines = append(outlines, *node.outline)
+		}
+	}
+	return outlines
+}
+
+func opmlOutline(entity types.Entity, isFeed bool, folderNames map[types.Label]struct{}) opml.Outline {
+	var href string
+	if entity.URI != nil {
+		href = entity.URI.String()
+	}
+
+	text := href
+	if names := types.MapToSortedSlice(entity.Names); len(names) > 0 {
+		text = names[0]
+	}
+
+	var categories []string
+	for _, label := range types.MapToSortedSlice(entity.Labels) {
+		if _, ok := folderNames[types.Label(label)]; !ok {
+			categories = append(categories, label)
+		}
+	}
+
+	outline := opml.Outline{
+		Text:     text,
+		Title:    text,
+		Category: strings.Join(categories, ","),
+		Created:  opml.FormatTime(time.Time(entity.CreatedAt)),
+	}
+
+	if isFeed {
+		outline.Type = opml.TypeRSS
+		outline.XMLURL = href
+	} else {
+		outline.Type = opml.TypeLink
+		outline.URL = href
+	}
+
+	if len(entity.Extended) > 0 {
+		outline.Description = string(entity.Extended[0])
+	}
+
+	return outline
+}
+
+func (f *OPMLFormatter) Format(w io.Writer, coll *types.Collection) error {
+	root := newOPMLTree(nil)
+
+	for entity := range coll.Entities() {
+		isFeed, _ := entity.IsFeed.Get()
+		if !isFeed && !f.All {
+			continue
+		}
+
+		folderNames := make(map[types.Label]struct{})
+		for _, path := range entity.Folders {
+			for _, folder := range path {
+				folderNames[types.Label(folder.Name)] = struct{}{}
+			}
+		}
+
+		outline := opmlOutline(entity, isFeed, folderNames)
+
+		paths := entity.Folders
+		if len(paths) == 0 {
+			paths = []types.FolderPath{nil}
+		}
+
+		for _, path := range paths {
+			t := root
+			for _, folder := range path {
+				t = t.subfolder(folder)
+			}
+			t.add(opmlNode{outline: &outline})
+		}
+	}
+
+	title := "Feeds"
+	if f.All {
+		title = "Bookmarks"
+	}
+
+	doc := opml.Document{
+		Version: opml.Version,
+		Head:    opml.Head{Title: title},
+		Body:    opml.Body{Outlines: buildOPMLOutlines(root.nodes)},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
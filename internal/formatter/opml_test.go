@@ -0,0 +1,93 @@
+package formatter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+const opmlHTMLInput = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><A HREF="https://go.dev/blog/feed.atom" ADD_DATE="1609200000" TAGS="go" FEED="true">Go Blog</A>
+    <DD>Posts & news
+    <DT><H3>News</H3>
+    <DL><p>
+        <DT><A HREF="https://lwn.net/headlines/rss" ADD_DATE="1609200000" TAGS="linux" FEED="true">LWN</A>
+        <DT><A HREF="https://lwn.net/" ADD_DATE="1609200000">LWN site</A>
+    </DL><p>
+</DL><p>
+`
+
+func TestOPMLFormatter(t *testing.T) {
+	html := &parser.HTMLParser{}
+	coll, err := html.Parse(strings.NewReader(opmlHTMLInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := (&OPMLFormatter{}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	const want = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head>
+    <title>Feeds</title>
+  </head>
+  <body>
+    <outline text="Go Blog" title="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" description="Posts &amp; news" category="go" created="Tue, 29 Dec 2020 00:00:00 +0000"></outline>
+    <outline text="News" title="News">
+      <outline text="LWN" title="LWN" type="rss" xmlUrl="https://lwn.net/headlines/rss" category="linux" created="Tue, 29 Dec 2020 00:00:00 +0000"></outline>
+    </outline>
+  </body>
+</opml>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestOPMLFormatterRoundTrip(t *testing.T) {
+	html := &parser.HTMLParser{}
+	coll, err := html.Parse(strings.NewReader(opmlHTMLInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := (&OPMLFormatter{All: true}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	opml := &parser.OPMLParser{}
+	got, err := opml.Parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("re-Parse: %v\n%s", err, buf.String())
+	}
+
+	entities := slices.Collect(got.Entities())
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 entities, got %d\n%s", len(entities), buf.String())
+	}
+
+	for i, want := range slices.Collect(coll.Entities()) {
+		entity := entities[i]
+		if entity.URI.String() != want.URI.String() {
+			t.Errorf("entity %d: URI = %s, want %s", i, entity.URI, want.URI)
+		}
+		if entity.CreatedAt.Unix() != want.CreatedAt.Unix() {
+			t.Errorf("entity %d: CreatedAt = %v, want %v", i, entity.CreatedAt, want.CreatedAt)
+		}
+		if !slices.Equal(types.MapToSortedSlice(entity.Labels), types.MapToSortedSlice(want.Labels)) {
+			t.Errorf("entity %d: Labels = %v, want %v", i, entity.Labels, want.Labels)
+		}
+		wantFeed, _ := want.IsFeed.Get()
+		if isFeed, _ := entity.IsFeed.Get(); isFeed != wantFeed {
+			t.Errorf("entity %d: IsFeed = %v, want %v", i, isFeed, wantFeed)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// OrgFormatter writes a collection in the structure read by
+// parser.OrgParser. As with MarkdownFormatter, entities are grouped under a
+// headline for the day they were created, then under nested label
+// headlines, and links and edges become nested list items, each entity
+// nested under its parent or earliest inserted neighbor in the same section.
+//
+// What that structure cannot express is written to a property drawer under
+// the item: a creation time other than midnight, update and visit times,
+// flags, further names, and extended descriptions. Org timestamps stop at
+// minutes and property values at the end of the line, so seconds are dropped
+// and line breaks in descriptions become spaces.
+type OrgFormatter struct{}
+
+// orgPathEscaper escapes the characters Org does not allow in link paths.
+var orgPathEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+
+type orgSection struct {
+	date   time.Time
+	labels []string
+	items  []int
+}
+
+type orgNode struct {
+	entity   types.Entity
+	date     time.Time
+	labels   []string
+	children []int
+}
+
+// formatOrgTime writes an inactive Org timestamp, leaving out the time of
+// day at midnight.
+func formatOrgTime(t time.Time) string {
+	t = t.UTC()
+	if t.Equal(utcDay(t)) {
+		return t.Format("[2006-01-02 Mon]")
+	}
+	return t.Format("[2006-01-02 Mon 15:04]")
+}
+
+func orgFlag(b bool) string {
+	if b {
+		return "t"
+	}
+	return "nil"
+}
+
+type orgProperty struct {
+	key   string
+	value string
+}
+
+// orgProperties lists the fields of entity that its list item and section
+// do not convey. The first name is carried by the link.
+func orgProperties(entity types.Entity, date time.Time) []orgProperty {
+	var props []orgProperty
+
+	if createdAt := time.Time(entity.CreatedAt); !createdAt.Equal(date) {
+		props = append(props, orgProperty{"CREATED", formatOrgTime(createdAt)})
+	}
+
+	if len(entity.UpdatedAt) > 0 {
+		stamps := make([]string, len(entity.UpdatedAt))
+		for i, updatedAt := range entity.UpdatedAt {
+			stamps[i] = formatOrgTime(time.Time(updatedAt))
+		}
+		props = append(props, orgProperty{"UPDATED", strings.Join(stamps, " ")})
+	}
+
+	if t, ok := entity.LastVisitedAt.Get(); ok {
+		props = append(props, orgProperty{"LAST_VISITED", formatOrgTime(t)})
+	}
+
+	if b, ok := entity.ToRead.Get(); ok {
+		props = append(props, orgProperty{"TOREAD", orgFlag(b)})
+	}
+	if b, ok := entity.Shared.Get(); ok {
+		props = append(props, orgProperty{"SHARED", orgFlag(b)})
+	}
+	if b, ok := entity.IsFeed.Get(); ok {
+		props = append(props, orgProperty{"FEED", orgFlag(b)})
+	}
+
+	repeated := func(key string, values []string) {
+		for i, value := range values {
+			prop := orgProperty{key, strings.Join(strings.Fields(value), " ")}
+			if i > 0 {
+				prop.key += "+"
+			}
+			props = append(props, prop)
+		}
+	}
+
+	if names := types.MapToSortedSlice(entity.Names); len(names) > 1 {
+		repeated("NAME", names[1:])
+	}
+
+	extended := make([]string, len(entity.Extended))
+	for i, e := range entity.Extended {
+		extended[i] = string(e)
+	}
+	repeated("EXTENDED", extended)
+
+	return props
+}
+
+func orgLink(entity types.Entity) string {
+	var href string
+	if entity.URI != nil {
+		href = orgPathEscaper.Replace(entity.URI.String())
+	}
+	if names := types.MapToSortedSlice(entity.Names); len(names) > 0 {
+		return fmt.Sprintf("[[%s][%s]]", href, names[0])
+	}
+	return fmt.Sprintf("[[%s]]", href)
+}
+
+func (f *OrgFormatter) Format(w io.Writer, coll *types.Collection) error {
+	var nodes []orgNode
+	position := make(map[types.Id]int)
+	ids := make([]types.Id, 0, coll.Len())
+
+	for id, entity := range coll.Nodes() {
+		position[id] = len(nodes)
+		ids = append(ids, id)
+		nodes = append(nodes, orgNode{
+			entity: entity,
+			date:   utcDay(time.Time(entity.CreatedAt)),
+			labels: types.MapToSortedSlice(entity.Labels),
+		})
+	}
+
+	sameSection := func(i, j int) bool {
+		return nodes[i].date.Equal(nodes[j].date) && slices.Equal(nodes[i].labels, nodes[j].labels)
+	}
+
+	sections := make(map[markdownSectionKey]*orgSection)
+	for i := range nodes {
+		parent := treeParent(coll, ids, position, i, sameSection)
+
+		if parent >= 0 {
+			nodes[parent].children = append(nodes[parent].children, i)
+			continue
+		}
+
+		node := &nodes[i]
+		key := markdownSectionKey{date: node.date, labels: strings.Join(node.labels, "\x00")}
+		sec, ok := sections[key]
+		if !ok {
+			sec = &orgSection{date: node.date, labels: node.labels}
+			sections[key] = sec
+		}
+		sec.items = append(sec.items, i)
+	}
+
+	ordered := make([]*orgSection, 0, len(sections))
+	for _, sec := range sections {
+		ordered = append(ordered, sec)
+	}
+	slices.SortFunc(ordered, func(a, b *orgSection) int {
+		if c := a.date.Compare(b.date); c != 0 {
+			return c
+		}
+		return slices.Compare(a.labels, b.labels)
+	})
+
+	bw := bufio.NewWriter(w)
+
+	var writeItem func(index int, indent string)
+	writeItem = func(index int, indent string) {
+		node := nodes[index]
+		fmt.Fprintf(bw, "%s- %s\n", indent, orgLink(node.entity))
+		if props := orgProperties(node.entity, node.date); len(props) > 0 {
+			fmt.Fprintf(bw, "%s  :PROPERTIES:\n", indent)
+			for _, prop := range props {
+				fmt.Fprintf(bw, "%s  :%s: %s\n", indent, prop.key, prop.value)
+			}
+			fmt.Fprintf(bw, "%s  :END:\n", indent)
+		}
+		for _, child := range node.children {
+			writeItem(child, indent+"  ")
+		}
+	}
+
+	var prev *orgSection
+	for _, sec := range ordered {
+		var prevLabels []string
+		if prev == nil || !sec.date.Equal(prev.date) {
+			if !sec.date.IsZero() {
+				fmt.Fprintf(bw, "* %s\n\n", formatOrgTime(sec.date))
+			}
+		} else {
+			prevLabels = prev.labels
+		}
+
+		common := 0
+		for common < len(prevLabels) && common < len(sec.labels) && prevLabels[common] == sec.labels[common] {
+			common++
+		}
+		for level := common; level < len(sec.labels); level++ {
+			fmt.Fprintf(bw, "%s %s\n\n", strings.Repeat("*", level+2), sec.labels[level])
+		}
+
+		for _, item := range sec.items {
+			writeItem(item, "")
+		}
+		bw.WriteString("\n")
+
+		prev = sec
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,108 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/henrytill/hbt-go/internal/parser"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func formatOrg(t *testing.T, coll *types.Collection) string {
+	t.Helper()
+	var buf strings.Builder
+	f := &OrgFormatter{}
+	if err := f.Format(&buf, coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	return buf.String()
+}
+
+func parseOrg(t *testing.T, input string) types.Collection {
+	t.Helper()
+	p := &parser.OrgParser{}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return coll
+}
+
+func TestOrgFormatterRoundTrip(t *testing.T) {
+	const input = `* [2023-11-15 Wed]
+
+- [[https://example.com/plain][Plain]]
+
+** Programming                                                       :dev:
+*** Go
+- [[https://go.dev/][Go]]
+  :PROPERTIES:
+  :CREATED: [2023-11-15 Wed 10:30]
+  :TOREAD: t
+  :EXTENDED: The Go homepage
+  :EXTENDED+: Downloads
+  :END:
+  - [[https://go.dev/tour/][Tour]]
+    - [[https://go.dev/tour/welcome/1]]
+  - [[https://example.com/a\[1\]][Bracketed]]
+
+* November 16, 2023
+- [[https://go.dev/][Go, again]]
+`
+	// Tags become labels, and labels nest as headlines in sorted order.
+	const want = `* [2023-11-15 Wed]
+
+- [[https://example.com/plain][Plain]]
+
+** Go
+
+*** Programming
+
+**** dev
+
+- [[https://go.dev/][Go]]
+  :PROPERTIES:
+  :CREATED: [2023-11-15 Wed 10:30]
+  :UPDATED: [2023-11-16 Thu]
+  :TOREAD: t
+  :NAME: Go, again
+  :EXTENDED: The Go homepage
+  :EXTENDED+: Downloads
+  :END:
+  - [[https://go.dev/tour/][Tour]]
+    - [[https://go.dev/tour/welcome/1]]
+  - [[https://example.com/a\[1\]][Bracketed]]
+
+`
+
+	coll := parseOrg(t, input)
+	first := formatOrg(t, &coll)
+	if first != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", first, want)
+	}
+
+	reparsed := parseOrg(t, first)
+	second := formatOrg(t, &reparsed)
+
+	if second != first {
+		t.Errorf("parse→format→parse not stable:\nfirst:\n%s\nsecond:\n%s", first, second)
+	}
+	if reparsed.Len() != coll.Len() {
+		t.Fatalf("entity count changed: got %d, want %d", reparsed.Len(), coll.Len())
+	}
+
+	originals := make(map[string]types.Entity)
+	for e := range coll.Entities() {
+		originals[e.URI.String()] = e
+	}
+	for e := range reparsed.Entities() {
+		orig, ok := originals[e.URI.String()]
+		if !ok {
+			t.Errorf("unexpected entity %s after round trip", e.URI)
+			continue
+		}
+		if !e.Equal(orig) {
+			t.Errorf("entity %s changed in round trip:\ngot:  %+v\nwant: %+v", e.URI, e, orig)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pinboard
+
+import (
+	"slices"
+
+	"github.com/henrytill/hbt-go/internal/pinboard"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// collectionPosts converts the collection's entities to posts, newest first
+// as in Pinboard's own exports. The sort is stable, so entities created at
+// the same instant keep their insertion order.
+func collectionPosts(coll *types.Collection) []pinboard.Post {
+	type keyedPost struct {
+		post      pinboard.Post
+		createdAt types.CreatedAt
+	}
+
+	keyed := make([]keyedPost, 0, coll.Len())
+	for entity := range coll.Entities() {
+		keyed = append(keyed, keyedPost{
+			post:      types.NewPostFromEntity(entity),
+			createdAt: entity.CreatedAt,
+		})
+	}
+
+	slices.SortStableFunc(keyed, func(a, b keyedPost) int {
+		switch {
+		case a.createdAt.After(b.createdAt):
+			return -1
+		case a.createdAt.Before(b.createdAt):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	posts := make([]pinboard.Post, len(keyed))
+	for i, k := range keyed {
+		posts[i] = k.post
+	}
+	return posts
+}
@@ -0,0 +1,18 @@
+package pinboard
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+type JSONFormatter struct{}
+
+func (f *JSONFormatter) Format(w io.Writer, coll *types.Collection) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(collectionPosts(coll))
+}
@@ -0,0 +1,96 @@
+package pinboard
+
+import (
+	"bytes"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pinboardparser "github.com/henrytill/hbt-go/internal/parser/pinboard"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func makeCollection(t *testing.T) types.Collection {
+	t.Helper()
+
+	coll := types.NewCollection()
+	for i, href := range []string{"https://example.com/old", "https://example.com/new?a=1&b=2"} {
+		u, err := url.Parse(href)
+		if err != nil {
+			t.Fatal(err)
+		}
+		coll.Upsert(types.Entity{
+			URI:       u,
+			CreatedAt: types.CreatedAt(time.Date(2021, 1, i+1, 0, 0, 0, 0, time.UTC)),
+			UpdatedAt: []types.UpdatedAt{},
+			Names:     map[types.Name]struct{}{types.Name(`Title <"quoted">`): {}},
+			Labels:    map[types.Label]struct{}{"go": {}, "web": {}},
+			Shared:    types.NewShared(i == 0),
+			ToRead:    types.NewToRead(i == 1),
+			IsFeed:    types.NewIsFeed(false),
+			Extended:  []types.Extended{"notes & more"},
+		})
+	}
+	return coll
+}
+
+func assertSameEntities(t *testing.T, got, want *types.Collection) {
+	t.Helper()
+	if got.Len() != want.Len() {
+		t.Fatalf("got %d entities, want %d", got.Len(), want.Len())
+	}
+	wantEntities := slices.Collect(want.Entities())
+	for i, g := range slices.Collect(got.Entities()) {
+		if !g.Equal(wantEntities[i]) {
+			t.Errorf("entity %d changed in round trip:\ngot:  %+v\nwant: %+v", i, g, wantEntities[i])
+		}
+	}
+}
+
+func TestJSONFormatterRoundTrip(t *testing.T) {
+	coll := makeCollection(t)
+
+	var buf bytes.Buffer
+	if err := (&JSONFormatter{}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Index(out, "/new") > strings.Index(out, "/old") {
+		t.Errorf("expected newest post first:\n%s", out)
+	}
+	if !strings.Contains(out, `"tags": "go web"`) {
+		t.Errorf("expected space-separated tags:\n%s", out)
+	}
+
+	got, err := (&pinboardparser.JSONParser{}).Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	assertSameEntities(t, &got, &coll)
+}
+
+func TestXMLFormatterRoundTrip(t *testing.T) {
+	coll := makeCollection(t)
+
+	var buf bytes.Buffer
+	if err := (&XMLFormatter{}).Format(&buf, &coll); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`+"\n<posts") {
+		t.Errorf("expected XML declaration and posts root:\n%s", out)
+	}
+	if !strings.Contains(out, `shared="yes"`) || !strings.Contains(out, `toread="yes"`) {
+		t.Errorf("expected yes/no flags:\n%s", out)
+	}
+
+	got, err := (&pinboardparser.XMLParser{}).Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	assertSameEntities(t, &got, &coll)
+}
@@ -0,0 +1,28 @@
+package pinboard
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/henrytill/hbt-go/internal/pinboard"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+type XMLFormatter struct{}
+
+func (f *XMLFormatter) Format(w io.Writer, coll *types.Collection) error {
+	posts := pinboard.Posts{Posts: collectionPosts(coll)}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(posts); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,102 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// RSSFormatter writes a collection as an RSS 2.0 feed, selecting and
+// describing entities as AtomFormatter does. Items are dated by the time they
+// were last bookmarked or updated, and their guid is the bookmarked URL.
+type RSSFormatter struct {
+	// Title titles the channel, defaulting to "Bookmarks".
+	Title string
+	// Link is the URL of the channel, which RSS requires; Format fails
+	// without it.
+	Link string
+	// Private includes entities that are not marked shared.
+	Private bool
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+func (f *RSSFormatter) Format(w io.Writer, coll *types.Collection) error {
+	if f.Link == "" {
+		return errors.New("an RSS channel needs a link, the URL it is published at")
+	}
+
+	entries := feedEntries(coll, f.Private)
+
+	title := f.Title
+	if title == "" {
+		title = defaultFeedTitle
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          f.Link,
+			Description:   title,
+			LastBuildDate: feedUpdated(entries).Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(entries)),
+		},
+	}
+
+	for _, e := range entries {
+		item := rssItem{
+			Title:      e.title,
+			Link:       e.href,
+			Categories: types.MapToSortedSlice(e.entity.Labels),
+			GUID:       rssGUID{IsPermaLink: true, Value: e.href},
+			PubDate:    e.updated.Format(time.RFC1123Z),
+		}
+		if len(e.entity.Extended) > 0 {
+			item.Description = string(e.entity.Extended[0])
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -6,27 +6,47 @@ import (
 	"os"
 
 	"github.com/goccy/go-yaml"
+	"github.com/henrytill/hbt-go/internal/delimited"
 )
 
 type Mappings map[string]string
 
-func LoadMappings(filename string) (Mappings, error) {
+// loadStringMap reads a YAML or JSON object of strings from filename. What
+// names the kind of file in errors.
+func loadStringMap(filename, what string) (map[string]string, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read mappings file: %w", err)
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
 	}
 
-	var ret Mappings
+	var ret map[string]string
 
 	if err := yaml.Unmarshal(data, &ret); err != nil {
 		if jsonErr := json.Unmarshal(data, &ret); jsonErr != nil {
-			return nil, fmt.Errorf("failed to parse mappings file as YAML or JSON: YAML error: %v, JSON error: %v", err, jsonErr)
+			return nil, fmt.Errorf("failed to parse %s as YAML or JSON: YAML error: %v, JSON error: %v", what, err, jsonErr)
 		}
 	}
 
 	if ret == nil {
-		ret = make(Mappings)
+		ret = make(map[string]string)
 	}
 
 	return ret, nil
 }
+
+func LoadMappings(filename string) (Mappings, error) {
+	return loadStringMap(filename, "mappings file")
+}
+
+// LoadColumns reads a CSV column mapping: an object from column headers to
+// the entity fields they hold.
+func LoadColumns(filename string) (delimited.Columns, error) {
+	columns, err := loadStringMap(filename, "columns file")
+	if err != nil {
+		return nil, err
+	}
+	if err := delimited.Columns(columns).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid columns file: %w", err)
+	}
+	return columns, nil
+}
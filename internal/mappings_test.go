@@ -85,3 +85,24 @@ func TestLoadMappingsMissingFile(t *testing.T) {
 		t.Error("expected error for missing file")
 	}
 }
+
+func TestLoadColumns(t *testing.T) {
+	path := writeMappingsFile(t, "columns.yaml", "folder: labels\ncreated: createdAt\nnote: \"\"\n")
+
+	got, err := LoadColumns(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got["folder"] != "labels" || got["created"] != "createdAt" || got["note"] != "" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestLoadColumnsUnknownField(t *testing.T) {
+	path := writeMappingsFile(t, "columns.yaml", "folder: directory\n")
+
+	if _, err := LoadColumns(path); err == nil {
+		t.Error("expected error for column mapped to an unknown field")
+	}
+}
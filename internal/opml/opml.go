@@ -0,0 +1,82 @@
+package opml
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Version is the OPML version written by the formatter. Readers also accept
+// 1.0 and 1.1 documents, which share the same outline attributes.
+const Version = "2.0"
+
+// Values of the outline type attribute.
+const (
+	TypeRSS  = "rss"
+	TypeLink = "link"
+)
+
+// Document is an OPML file as exchanged by feed readers: a head with a title
+// and a body of nested outlines. Outlines without a type group the outlines
+// they contain, as folders do.
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title string `xml:"title,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is a subscription (type "rss", with XMLURL), a link (type "link",
+// with URL), or a group of outlines. Category holds comma-separated,
+// slash-delimited category paths, and Created an RFC 822 date.
+type Outline struct {
+	Text        string    `xml:"text,attr"`
+	Title       string    `xml:"title,attr,omitempty"`
+	Type        string    `xml:"type,attr,omitempty"`
+	XMLURL      string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL     string    `xml:"htmlUrl,attr,omitempty"`
+	URL         string    `xml:"url,attr,omitempty"`
+	Description string    `xml:"description,attr,omitempty"`
+	Category    string    `xml:"category,attr,omitempty"`
+	Created     string    `xml:"created,attr,omitempty"`
+	Outlines    []Outline `xml:"outline"`
+}
+
+// timeLayouts are the date layouts accepted by ParseTime. OPML specifies RFC
+// 822 dates, which are written with and without seconds, numeric zones, and
+// four-digit years in practice.
+var timeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+}
+
+// ParseTime parses an outline date. It reports false for dates in none of
+// the accepted layouts.
+func ParseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// FormatTime formats an outline date. The zero time is written as an empty
+// string, which omits the attribute.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC1123Z)
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/chrome"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// ChromeParser reads a Chromium profile's Bookmarks file. Folders become
+// labels, and each entity records its folder path. The bookmark bar and
+// mobile roots start that path, as they do in Chromium's HTML export, but do
+// not become labels; bookmarks under the other root have no root folder.
+// The checksum is not verified, as Chromium itself only uses it to detect
+// edits made outside the browser.
+type ChromeParser struct{}
+
+func chromeFolder(node *chrome.Node) types.Folder {
+	return types.Folder{
+		Name:         strings.TrimSpace(node.Name),
+		AddDate:      chrome.ParseTime(node.DateAdded),
+		LastModified: chrome.ParseTime(node.DateModified),
+	}
+}
+
+func addChromeBookmark(
+	coll *types.Collection,
+	node *chrome.Node,
+	folders []types.Folder,
+	labelStart int,
+) error {
+	if node.URL == "" {
+		return nil
+	}
+
+	parsedURL, err := url.Parse(node.URL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL %s: %w", node.URL, err)
+	}
+
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+	}
+
+	createdAt := chrome.ParseTime(node.DateAdded)
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var lastVisitedAt types.LastVisitedAt
+	if lastUsed := chrome.ParseTime(node.DateLastUsed); !lastUsed.IsZero() {
+		lastVisitedAt = types.NewLastVisitedAt(lastUsed)
+	}
+
+	names := make(map[types.Name]struct{})
+	if name := strings.TrimSpace(node.Name); name != "" {
+		names[types.Name(name)] = struct{}{}
+	}
+
+	labels := make(map[types.Label]struct{})
+	for _, folder := range folders[labelStart:] {
+		labels[types.Label(folder.Name)] = struct{}{}
+	}
+
+	entity := types.Entity{
+		URI:           parsedURL,
+		CreatedAt:     types.CreatedAt(createdAt),
+		UpdatedAt:     []types.UpdatedAt{},
+		Names:         names,
+		Labels:        labels,
+		LastVisitedAt: lastVisitedAt,
+	}
+
+	if len(folders) > 0 {
+		entity.Folders = []types.FolderPath{slices.Clone(folders)}
+	}
+
+	coll.Upsert(entity)
+
+	return nil
+}
+
+func walkChromeFolder(
+	coll *types.Collection,
+	folder *chrome.Node,
+	folders []types.Folder,
+	labelStart int,
+) error {
+	for i := range folder.Children {
+		child := &folder.Children[i]
+		switch child.Type {
+		case chrome.TypeURL:
+			if err := addChromeBookmark(coll, child, folders, labelStart); err != nil {
+				return err
+			}
+		case chrome.TypeFolder:
+			path := folders
+			if sub := chromeFolder(child); sub.Name != "" {
+				path = append(slices.Clip(folders), sub)
+			}
+			if err := walkChromeFolder(coll, child, path, labelStart); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *ChromeParser) Parse(r io.Reader) (types.Collection, error) {
+	var bookmarks chrome.Bookmarks
+
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(&bookmarks); err != nil {
+		return types.Collection{}, err
+	}
+
+	roots := []struct {
+		node        *chrome.Node
+		inPath      bool
+		defaultName string
+	}{
+		{&bookmarks.Roots.BookmarkBar, true, chrome.BookmarkBarName},
+		{&bookmarks.Roots.Other, false, chrome.OtherName},
+		{&bookmarks.Roots.Synced, true, chrome.SyncedName},
+	}
+
+	coll := types.NewCollection()
+	for _, root := range roots {
+		var folders []types.Folder
+		if root.inPath {
+			folder := chromeFolder(root.node)
+			if folder.Name == "" {
+				folder.Name = root.defaultName
+			}
+			folders = []types.Folder{folder}
+		}
+		if err := walkChromeFolder(&coll, root.node, folders, len(folders)); err != nil {
+			return types.Collection{}, err
+		}
+	}
+
+	return coll, nil
+}
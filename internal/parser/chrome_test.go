@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+const chromeBookmarks = `{
+   "checksum": "ignored",
+   "roots": {
+      "bookmark_bar": {
+         "children": [ {
+            "date_added": "13253673600000000",
+            "date_last_used": "13253760000000000",
+            "guid": "00000000-0000-4000-8000-000000000004",
+            "id": "4",
+            "name": "Go",
+            "type": "url",
+            "url": "https://go.dev"
+         }, {
+            "children": [ {
+               "date_added": "13253673600000000",
+               "date_last_used": "0",
+               "guid": "00000000-0000-4000-8000-000000000006",
+               "id": "6",
+               "name": "Nested",
+               "type": "url",
+               "url": "https://example.com/nested"
+            } ],
+            "date_added": "13253673600000000",
+            "date_last_used": "0",
+            "date_modified": "13253760000000000",
+            "guid": "00000000-0000-4000-8000-000000000005",
+            "id": "5",
+            "name": "Reading",
+            "type": "folder"
+         } ],
+         "date_added": "0",
+         "date_last_used": "0",
+         "date_modified": "0",
+         "guid": "0bc5d13f-2cba-5d74-951f-3f233fe6c908",
+         "id": "1",
+         "name": "Bookmarks bar",
+         "type": "folder"
+      },
+      "other": {
+         "children": [ {
+            "date_added": "13253673600000000",
+            "date_last_used": "0",
+            "guid": "00000000-0000-4000-8000-000000000007",
+            "id": "7",
+            "name": "Loose",
+            "type": "url",
+            "url": "https://example.com/loose"
+         } ],
+         "date_added": "0",
+         "date_last_used": "0",
+         "date_modified": "0",
+         "guid": "82b081ec-3dd3-529c-8475-ab6c344590dd",
+         "id": "2",
+         "name": "Other bookmarks",
+         "type": "folder"
+      },
+      "synced": {
+         "children": [ ],
+         "date_added": "0",
+         "date_last_used": "0",
+         "date_modified": "0",
+         "guid": "4cf2e351-0e85-532b-bb37-df045d8f8d0f",
+         "id": "3",
+         "name": "Mobile bookmarks",
+         "type": "folder"
+      }
+   },
+   "version": 1
+}
+`
+
+func TestChromeParser(t *testing.T) {
+	p := &ChromeParser{}
+	coll, err := p.Parse(strings.NewReader(chromeBookmarks))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	entities := slices.Collect(coll.Entities())
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 entities, got %d", len(entities))
+	}
+
+	created := time.Date(2020, 12, 29, 0, 0, 0, 0, time.UTC)
+
+	goDev := entities[0]
+	if goDev.URI.String() != "https://go.dev/" {
+		t.Errorf("URI = %s, want https://go.dev/", goDev.URI)
+	}
+	if !time.Time(goDev.CreatedAt).Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", time.Time(goDev.CreatedAt), created)
+	}
+	if lastUsed, ok := goDev.LastVisitedAt.Get(); !ok || !lastUsed.Equal(created.AddDate(0, 0, 1)) {
+		t.Errorf("LastVisitedAt = (%v, %v), want (%v, true)", lastUsed, ok, created.AddDate(0, 0, 1))
+	}
+	if len(goDev.Labels) != 0 {
+		t.Errorf("root folder should not be a label, got %v", goDev.Labels)
+	}
+	if len(goDev.Folders) != 1 || goDev.Folders[0][0].Name != "Bookmarks bar" {
+		t.Errorf("Folders = %v, want [[Bookmarks bar]]", goDev.Folders)
+	}
+
+	nested := entities[1]
+	if _, ok := nested.Labels[types.Label("Reading")]; !ok || len(nested.Labels) != 1 {
+		t.Errorf("Labels = %v, want [Reading]", nested.Labels)
+	}
+	if _, ok := nested.LastVisitedAt.Get(); ok {
+		t.Error("date_last_used of 0 should leave LastVisitedAt unset")
+	}
+	if len(nested.Folders) != 1 || len(nested.Folders[0]) != 2 {
+		t.Fatalf("Folders = %v, want [[Bookmarks bar Reading]]", nested.Folders)
+	}
+	reading := nested.Folders[0][1]
+	if !reading.LastModified.Equal(created.AddDate(0, 0, 1)) {
+		t.Errorf("folder LastModified = %v, want %v", reading.LastModified, created.AddDate(0, 0, 1))
+	}
+
+	loose := entities[2]
+	if len(loose.Folders) != 0 || len(loose.Labels) != 0 {
+		t.Errorf("bookmark under the other root: Folders = %v, Labels = %v, want none", loose.Folders, loose.Labels)
+	}
+}
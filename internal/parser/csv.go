@@ -0,0 +1,239 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/delimited"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// CSVParser reads delimited text whose first row names the columns. Columns
+// named after an entity field, in any case, hold that field; Columns maps
+// other headers, so that exports from other services can be read. Columns
+// that hold no field are ignored, and every row needs a uri. Times are Unix
+// seconds or RFC 3339, and a row without a creation time is dated now.
+type CSVParser struct {
+	// Comma separates cells, defaulting to ','.
+	Comma rune
+	// Separator splits the values of multi-valued fields within a cell,
+	// defaulting to delimited.DefaultSeparator.
+	Separator string
+	// Columns maps column headers to field names.
+	Columns delimited.Columns
+}
+
+// utf8BOM starts the CSV files written by some spreadsheets.
+const utf8BOM = "\ufeff"
+
+func (p *CSVParser) columnFields(header []string) ([]string, error) {
+	if err := p.Columns.Validate(); err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, len(header))
+	hasURI := false
+	for i, column := range header {
+		if field, ok := p.Columns[column]; ok {
+			fields[i] = delimited.FieldOf(field)
+		} else {
+			fields[i] = delimited.FieldOf(column)
+		}
+		hasURI = hasURI || fields[i] == delimited.FieldURI
+	}
+
+	if !hasURI {
+		return nil, fmt.Errorf("no column holds field %s", delimited.FieldURI)
+	}
+
+	return fields, nil
+}
+
+func csvTimes(values []string) ([]time.Time, error) {
+	times := make([]time.Time, 0, len(values))
+	for _, value := range values {
+		t, err := delimited.ParseTime(value)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// csvEntity builds an entity from the values of one row, keyed by field.
+// Multi-valued fields are already split.
+func csvEntity(row map[string][]string) (types.Entity, error) {
+	first := func(field string) string {
+		if values := row[field]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	href := first(delimited.FieldURI)
+	if href == "" {
+		return types.Entity{}, fmt.Errorf("missing %s", delimited.FieldURI)
+	}
+
+	parsedURL, err := url.Parse(href)
+	if err != nil {
+		return types.Entity{}, fmt.Errorf("failed to parse URL %s: %w", href, err)
+	}
+
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+	}
+
+	createdAt := time.Now()
+	if value := first(delimited.FieldCreatedAt); value != "" {
+		if createdAt, err = delimited.ParseTime(value); err != nil {
+			return types.Entity{}, fmt.Errorf("%s: %w", delimited.FieldCreatedAt, err)
+		}
+	}
+
+	times, err := csvTimes(row[delimited.FieldUpdatedAt])
+	if err != nil {
+		return types.Entity{}, fmt.Errorf("%s: %w", delimited.FieldUpdatedAt, err)
+	}
+	updatedAt := make([]types.UpdatedAt, len(times))
+	for i, t := range times {
+		updatedAt[i] = types.UpdatedAt(t)
+	}
+
+	names := make(map[types.Name]struct{})
+	for _, name := range row[delimited.FieldNames] {
+		names[types.Name(name)] = struct{}{}
+	}
+
+	labels := make(map[types.Label]struct{})
+	for _, label := range row[delimited.FieldLabels] {
+		labels[types.Label(label)] = struct{}{}
+	}
+
+	entity := types.Entity{
+		URI:       parsedURL,
+		CreatedAt: types.CreatedAt(createdAt),
+		UpdatedAt: updatedAt,
+		Names:     names,
+		Labels:    labels,
+	}
+
+	flags := []struct {
+		field string
+		set   func(bool)
+	}{
+		{delimited.FieldShared, func(b bool) { entity.Shared = types.NewShared(b) }},
+		{delimited.FieldToRead, func(b bool) { entity.ToRead = types.NewToRead(b) }},
+		{delimited.FieldIsFeed, func(b bool) { entity.IsFeed = types.NewIsFeed(b) }},
+	}
+	for _, flag := range flags {
+		if value := first(flag.field); value != "" {
+			b, err := delimited.ParseBool(value)
+			if err != nil {
+				return types.Entity{}, fmt.Errorf("%s: %w", flag.field, err)
+			}
+			flag.set(b)
+		}
+	}
+
+	for _, extended := range row[delimited.FieldExtended] {
+		entity.Extended = append(entity.Extended, types.Extended(extended))
+	}
+
+	if value := first(delimited.FieldLastVisitedAt); value != "" {
+		t, err := delimited.ParseTime(value)
+		if err != nil {
+			return types.Entity{}, fmt.Errorf("%s: %w", delimited.FieldLastVisitedAt, err)
+		}
+		entity.LastVisitedAt = types.NewLastVisitedAt(t)
+	}
+
+	return entity, nil
+}
+
+func (p *CSVParser) Parse(r io.Reader) (types.Collection, error) {
+	reader := csv.NewReader(r)
+	if p.Comma != 0 {
+		reader.Comma = p.Comma
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	separator := p.Separator
+	if separator == "" {
+		separator = delimited.DefaultSeparator
+	}
+
+	coll := types.NewCollection()
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return coll, nil
+	}
+	if err != nil {
+		return types.Collection{}, fmt.Errorf("failed to parse header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], utf8BOM)
+	}
+
+	fields, err := p.columnFields(header)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return types.Collection{}, err
+		}
+
+		row := make(map[string][]string)
+		for i, cell := range record {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+			var values []string
+			switch {
+			case delimited.IsMultiValued(fields[i]):
+				values = delimited.Split(cell, separator)
+			case fields[i] == delimited.FieldExtended:
+				// Notes are kept as written, unless they are blank.
+				if strings.TrimSpace(cell) != "" {
+					values = []string{cell}
+				}
+			default:
+				values = []string{strings.TrimSpace(cell)}
+			}
+			for _, value := range values {
+				if value != "" {
+					row[fields[i]] = append(row[fields[i]], value)
+				}
+			}
+		}
+
+		// Spreadsheets often end with rows that hold nothing.
+		if len(row) == 0 {
+			continue
+		}
+
+		entity, err := csvEntity(row)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return types.Collection{}, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		coll.Upsert(entity)
+	}
+
+	return coll, nil
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/delimited"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func TestCSVParser(t *testing.T) {
+	const input = "\ufeffURI,createdAt,names,labels,toRead,lastVisitedAt,unknown\n" +
+		"https://go.dev,1609200000,\"Go\nThe Go Programming Language\",go,yes,2020-12-30T00:00:00Z,ignored\n" +
+		",,,,,,\n" +
+		"https://example.com/a,2020-12-29T00:00:00Z,,,,,\n"
+
+	p := &CSVParser{}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	entities := slices.Collect(coll.Entities())
+	if len(entities) != 2 {
+		t.Fatalf("expected 2 entities, got %d", len(entities))
+	}
+
+	created := time.Date(2020, 12, 29, 0, 0, 0, 0, time.UTC)
+
+	goDev := entities[0]
+	if goDev.URI.String() != "https://go.dev/" {
+		t.Errorf("URI = %s, want https://go.dev/", goDev.URI)
+	}
+	if !time.Time(goDev.CreatedAt).Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", time.Time(goDev.CreatedAt), created)
+	}
+	wantNames := []string{"Go", "The Go Programming Language"}
+	if names := types.MapToSortedSlice(goDev.Names); !slices.Equal(names, wantNames) {
+		t.Errorf("Names = %v, want %v", names, wantNames)
+	}
+	if toRead, ok := goDev.ToRead.Get(); !ok || !toRead {
+		t.Errorf("ToRead = (%v, %v), want (true, true)", toRead, ok)
+	}
+	if _, ok := goDev.Shared.Get(); ok {
+		t.Error("Shared should be unset without a column")
+	}
+	if visited, ok := goDev.LastVisitedAt.Get(); !ok || !visited.Equal(created.AddDate(0, 0, 1)) {
+		t.Errorf("LastVisitedAt = (%v, %v), want (%v, true)", visited, ok, created.AddDate(0, 0, 1))
+	}
+
+	if !time.Time(entities[1].CreatedAt).Equal(created) {
+		t.Errorf("RFC 3339 CreatedAt = %v, want %v", time.Time(entities[1].CreatedAt), created)
+	}
+}
+
+func TestCSVParserColumns(t *testing.T) {
+	const input = "title\turl\tfolder\ttags\tcreated\tnote\n" +
+		"Go\thttps://go.dev/\tWork\tgo, lang\t1609200000\tnot read\n"
+
+	p := &CSVParser{
+		Comma:     '\t',
+		Separator: ",",
+		Columns: delimited.Columns{
+			"title":   "names",
+			"url":     "uri",
+			"folder":  "labels",
+			"tags":    "labels",
+			"created": "createdAt",
+			"note":    "",
+		},
+	}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	entities := slices.Collect(coll.Entities())
+	if len(entities) != 1 {
+		t.Fatalf("expected 1 entity, got %d", len(entities))
+	}
+
+	wantLabels := []string{"Work", "go", "lang"}
+	if labels := types.MapToSortedSlice(entities[0].Labels); !slices.Equal(labels, wantLabels) {
+		t.Errorf("Labels = %v, want %v", labels, wantLabels)
+	}
+	if len(entities[0].Extended) != 0 {
+		t.Errorf("ignored column was read: Extended = %v", entities[0].Extended)
+	}
+}
+
+func TestCSVParserErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		columns delimited.Columns
+	}{
+		{"no uri column", "name,labels\nGo,go\n", nil},
+		{"missing uri", "uri,names\n,Go\n", nil},
+		{"bad time", "uri,createdAt\nhttps://go.dev/,yesterday\n", nil},
+		{"bad boolean", "uri,shared\nhttps://go.dev/,maybe\n", nil},
+		{"unknown field", "url\nhttps://go.dev/\n", delimited.Columns{"url": "link"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &CSVParser{Columns: tt.columns}
+			if _, err := p.Parse(strings.NewReader(tt.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package parser
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+
+	// Registers the pure-Go "sqlite" driver.
+	_ "modernc.org/sqlite"
+)
+
+// FirefoxParser reads bookmarks from a copy of a Firefox profile's
+// places.sqlite database. Bookmark titles become names, with the page title
+// as a fallback, and both Firefox tags and the folders a bookmark is filed
+// under become labels. The toolbar, "Other Bookmarks", and mobile roots start
+// each folder path, as in Firefox's HTML export, but do not become labels;
+// bookmarks in the menu root have no root folder. Visits come from
+// moz_places.last_visit_date. Keywords have no counterpart in an entity and
+// are not read.
+type FirefoxParser struct{}
+
+// Values of moz_bookmarks.type.
+const (
+	firefoxTypeBookmark = 1
+	firefoxTypeFolder   = 2
+)
+
+// GUIDs of the permanent root folders in moz_bookmarks.
+const (
+	firefoxRootGUID    = "root________"
+	firefoxToolbarGUID = "toolbar_____"
+	firefoxTagsGUID    = "tags________"
+	firefoxUnfiledGUID = "unfiled_____"
+	firefoxMobileGUID  = "mobile______"
+)
+
+// firefoxRootNames names the roots that start a folder path, matching the
+// headings of Firefox's HTML export.
+var firefoxRootNames = map[string]string{
+	firefoxToolbarGUID: "Bookmarks Toolbar",
+	firefoxUnfiledGUID: "Other Bookmarks",
+	firefoxMobileGUID:  "Mobile Bookmarks",
+}
+
+type firefoxBookmark struct {
+	id           int64
+	kind         int64
+	place        sql.NullInt64
+	parent       int64
+	title        sql.NullString
+	dateAdded    sql.NullInt64
+	lastModified sql.NullInt64
+	guid         string
+	children     []*firefoxBookmark
+}
+
+type firefoxPlace struct {
+	url           string
+	title         sql.NullString
+	lastVisitDate sql.NullInt64
+}
+
+// firefoxTime converts a PRTime, microseconds since the Unix epoch, to a
+// time. Missing and non-positive values yield the zero time.
+func firefoxTime(v sql.NullInt64) time.Time {
+	if !v.Valid || v.Int64 <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(v.Int64)
+}
+
+type firefoxReader struct {
+	coll   *types.Collection
+	places map[int64]firefoxPlace
+	tags   map[int64][]string
+}
+
+func loadFirefoxBookmarks(db *sql.DB) (map[int64]*firefoxBookmark, error) {
+	rows, err := db.Query(`
+		SELECT id, type, fk, parent, title, dateAdded, lastModified, guid
+		FROM moz_bookmarks
+		ORDER BY parent, position, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moz_bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	bookmarks := make(map[int64]*firefoxBookmark)
+	var ordered []*firefoxBookmark
+	for rows.Next() {
+		var b firefoxBookmark
+		var parent sql.NullInt64
+		if err := rows.Scan(&b.id, &b.kind, &b.place, &parent, &b.title, &b.dateAdded, &b.lastModified, &b.guid); err != nil {
+			return nil, fmt.Errorf("failed to read moz_bookmarks: %w", err)
+		}
+		b.parent = parent.Int64
+		bookmarks[b.id] = &b
+		ordered = append(ordered, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read moz_bookmarks: %w", err)
+	}
+
+	for _, b := range ordered {
+		if parent, ok := bookmarks[b.parent]; ok && parent != b {
+			parent.children = append(parent.children, b)
+		}
+	}
+
+	return bookmarks, nil
+}
+
+func loadFirefoxPlaces(db *sql.DB) (map[int64]firefoxPlace, error) {
+	rows, err := db.Query(`
+		SELECT id, url, title, last_visit_date
+		FROM moz_places
+		WHERE id IN (SELECT fk FROM moz_bookmarks WHERE fk IS NOT NULL)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moz_places: %w", err)
+	}
+	defer rows.Close()
+
+	places := make(map[int64]firefoxPlace)
+	for rows.Next() {
+		var id int64
+		var p firefoxPlace
+		if err := rows.Scan(&id, &p.url, &p.title, &p.lastVisitDate); err != nil {
+			return nil, fmt.Errorf("failed to read moz_places: %w", err)
+		}
+		places[id] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read moz_places: %w", err)
+	}
+
+	return places, nil
+}
+
+// readTags collects the tags of each place. Every folder under the tags root
+// is a tag, holding one bookmark per tagged place.
+func readTags(tagsRoot *firefoxBookmark) map[int64][]string {
+	tags := make(map[int64][]string)
+	if tagsRoot == nil {
+		return tags
+	}
+	for _, tag := range tagsRoot.children {
+		name := strings.TrimSpace(tag.title.String)
+		if tag.kind != firefoxTypeFolder || name == "" {
+			continue
+		}
+		for _, b := range tag.children {
+			if b.kind == firefoxTypeBookmark && b.place.Valid {
+				tags[b.place.Int64] = append(tags[b.place.Int64], name)
+			}
+		}
+	}
+	return tags
+}
+
+func (r *firefoxReader) add(b *firefoxBookmark, folders []types.Folder, labelStart int) error {
+	if !b.place.Valid {
+		return nil
+	}
+	place, ok := r.places[b.place.Int64]
+	if !ok || place.url == "" {
+		return nil
+	}
+
+	parsedURL, err := url.Parse(place.url)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL %s: %w", place.url, err)
+	}
+
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+	}
+
+	createdAt := firefoxTime(b.dateAdded)
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	updatedAt := []types.UpdatedAt{}
+	if modified := firefoxTime(b.lastModified); modified.After(createdAt) {
+		updatedAt = append(updatedAt, types.UpdatedAt(modified))
+	}
+
+	var lastVisitedAt types.LastVisitedAt
+	if visited := firefoxTime(place.lastVisitDate); !visited.IsZero() {
+		lastVisitedAt = types.NewLastVisitedAt(visited)
+	}
+
+	names := make(map[types.Name]struct{})
+	title := strings.TrimSpace(b.title.String)
+	if title == "" {
+		title = strings.TrimSpace(place.title.String)
+	}
+	if title != "" {
+		names[types.Name(title)] = struct{}{}
+	}
+
+	labels := make(map[types.Label]struct{})
+	for _, tag := range r.tags[b.place.Int64] {
+		labels[types.Label(tag)] = struct{}{}
+	}
+	for _, folder := range folders[labelStart:] {
+		labels[types.Label(folder.Name)] = struct{}{}
+	}
+
+	entity := types.Entity{
+		URI:           parsedURL,
+		CreatedAt:     types.CreatedAt(createdAt),
+		UpdatedAt:     updatedAt,
+		Names:         names,
+		Labels:        labels,
+		LastVisitedAt: lastVisitedAt,
+	}
+
+	if len(folders) > 0 {
+		entity.Folders = []types.FolderPath{slices.Clone(folders)}
+	}
+
+	r.coll.Upsert(entity)
+
+	return nil
+}
+
+func (r *firefoxReader) walk(folder *firefoxBookmark, folders []types.Folder, labelStart int) error {
+	for _, child := range folder.children {
+		switch child.kind {
+		case firefoxTypeBookmark:
+			if err := r.add(child, folders, labelStart); err != nil {
+				return err
+			}
+		case firefoxTypeFolder:
+			path := folders
+			sub := types.Folder{
+				Name:         strings.TrimSpace(child.title.String),
+				AddDate:      firefoxTime(child.dateAdded),
+				LastModified: firefoxTime(child.lastModified),
+			}
+			if sub.Name != "" {
+				path = append(slices.Clip(folders), sub)
+			}
+			if err := r.walk(child, path, labelStart); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parsePlaces(db *sql.DB) (types.Collection, error) {
+	bookmarks, err := loadFirefoxBookmarks(db)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	places, err := loadFirefoxPlaces(db)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	byGUID := make(map[string]*firefoxBookmark)
+	for _, b := range bookmarks {
+		byGUID[b.guid] = b
+	}
+
+	root, ok := byGUID[firefoxRootGUID]
+	if !ok {
+		return types.Collection{}, fmt.Errorf("places database has no bookmarks root")
+	}
+
+	coll := types.NewCollection()
+	r := firefoxReader{
+		coll:   &coll,
+		places: places,
+		tags:   readTags(byGUID[firefoxTagsGUID]),
+	}
+
+	for _, top := range root.children {
+		if top.guid == firefoxTagsGUID {
+			continue
+		}
+
+		var folders []types.Folder
+		if name, ok := firefoxRootNames[top.guid]; ok {
+			folders = []types.Folder{{
+				Name:         name,
+				AddDate:      firefoxTime(top.dateAdded),
+				LastModified: firefoxTime(top.lastModified),
+			}}
+		}
+
+		if err := r.walk(top, folders, len(folders)); err != nil {
+			return types.Collection{}, err
+		}
+	}
+
+	return coll, nil
+}
+
+func (p *FirefoxParser) Parse(r io.Reader) (types.Collection, error) {
+	// SQLite can only open a file, so the input is copied to one first. It
+	// goes in a directory of its own, as SQLite creates -wal and -shm files
+	// beside a database in WAL mode, which Firefox uses.
+	dir, err := os.MkdirTemp("", "hbt-places-*")
+	if err != nil {
+		return types.Collection{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp, err := os.Create(filepath.Join(dir, "places.sqlite"))
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return types.Collection{}, fmt.Errorf("failed to copy places database: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return types.Collection{}, fmt.Errorf("failed to copy places database: %w", err)
+	}
+
+	dsn := (&url.URL{Scheme: "file", Path: tmp.Name(), RawQuery: "mode=ro"}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return types.Collection{}, fmt.Errorf("failed to open places database: %w", err)
+	}
+	defer db.Close()
+
+	return parsePlaces(db)
+}
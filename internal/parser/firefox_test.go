@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// firefoxSchema is the subset of the places.sqlite schema the parser reads.
+const firefoxSchema = `
+CREATE TABLE moz_places (
+	id INTEGER PRIMARY KEY,
+	url LONGVARCHAR,
+	title LONGVARCHAR,
+	last_visit_date INTEGER
+);
+CREATE TABLE moz_bookmarks (
+	id INTEGER PRIMARY KEY,
+	type INTEGER,
+	fk INTEGER DEFAULT NULL,
+	parent INTEGER,
+	position INTEGER,
+	title LONGVARCHAR,
+	keyword_id INTEGER,
+	folder_type TEXT,
+	dateAdded INTEGER,
+	lastModified INTEGER,
+	guid TEXT UNIQUE
+);
+`
+
+// micros returns the PRTime for the given Unix time in seconds.
+func micros(sec int64) int64 {
+	return sec * 1000000
+}
+
+func buildPlacesDatabase(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "places.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(firefoxSchema); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+
+	places := []struct {
+		id        int64
+		url       string
+		title     any
+		lastVisit any
+	}{
+		{1, "https://go.dev", "The Go Programming Language", micros(500)},
+		{2, "https://example.com/nested", "Page title", nil},
+		{3, "https://example.com/menu", nil, nil},
+	}
+	for _, p := range places {
+		if _, err := db.Exec(`INSERT INTO moz_places (id, url, title, last_visit_date) VALUES (?, ?, ?, ?)`,
+			p.id, p.url, p.title, p.lastVisit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bookmarks := []struct {
+		id, kind     int64
+		fk           any
+		parent       int64
+		position     int64
+		title        any
+		dateAdded    int64
+		lastModified int64
+		guid         string
+	}{
+		{1, 2, nil, 0, 0, "", 0, 0, "root________"},
+		{2, 2, nil, 1, 0, "menu", 0, 0, "menu________"},
+		{3, 2, nil, 1, 1, "toolbar", micros(10), micros(20), "toolbar_____"},
+		{4, 2, nil, 1, 2, "tags", 0, 0, "tags________"},
+		{5, 2, nil, 1, 3, "unfiled", 0, 0, "unfiled_____"},
+		{6, 2, nil, 1, 4, "mobile", 0, 0, "mobile______"},
+		// Toolbar: a bookmark, then a folder holding another.
+		{10, 1, 1, 3, 0, "Go", micros(100), micros(200), "bookmark_go_"},
+		{11, 2, nil, 3, 1, "Reading", micros(50), micros(60), "folder_read_"},
+		{12, 1, 2, 11, 0, nil, micros(300), micros(300), "bookmark_nes"},
+		// Menu: a bookmark with no title on either side.
+		{13, 1, 3, 2, 0, nil, micros(400), micros(400), "bookmark_men"},
+		// Tags: "lang" on go.dev, "lang" and "later" on the nested page.
+		{20, 2, nil, 4, 0, "lang", 0, 0, "tag_lang____"},
+		{21, 1, 1, 20, 0, nil, 0, 0, "tag_lang_go_"},
+		{22, 1, 2, 20, 1, nil, 0, 0, "tag_lang_nes"},
+		{23, 2, nil, 4, 1, "later", 0, 0, "tag_later___"},
+		{24, 1, 2, 23, 0, nil, 0, 0, "tag_later_ne"},
+	}
+	for _, b := range bookmarks {
+		if _, err := db.Exec(`INSERT INTO moz_bookmarks
+			(id, type, fk, parent, position, title, dateAdded, lastModified, guid)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			b.id, b.kind, b.fk, b.parent, b.position, b.title, b.dateAdded, b.lastModified, b.guid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+func TestFirefoxParser(t *testing.T) {
+	f, err := os.Open(buildPlacesDatabase(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	p := &FirefoxParser{}
+	coll, err := p.Parse(f)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	entities := slices.Collect(coll.Entities())
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 entities, got %d", len(entities))
+	}
+
+	// Roots are visited in position order: menu before toolbar.
+	menu := entities[0]
+	if menu.URI.String() != "https://example.com/menu" {
+		t.Fatalf("first entity = %s, want the menu bookmark", menu.URI)
+	}
+	if len(menu.Names) != 0 || len(menu.Labels) != 0 || len(menu.Folders) != 0 {
+		t.Errorf("menu bookmark: Names = %v, Labels = %v, Folders = %v, want none",
+			menu.Names, menu.Labels, menu.Folders)
+	}
+
+	goDev := entities[1]
+	if goDev.URI.String() != "https://go.dev/" {
+		t.Errorf("URI = %s, want https://go.dev/", goDev.URI)
+	}
+	if names := types.MapToSortedSlice(goDev.Names); !slices.Equal(names, []string{"Go"}) {
+		t.Errorf("Names = %v, want the bookmark title [Go]", names)
+	}
+	if labels := types.MapToSortedSlice(goDev.Labels); !slices.Equal(labels, []string{"lang"}) {
+		t.Errorf("Labels = %v, want [lang]", labels)
+	}
+	if !time.Time(goDev.CreatedAt).Equal(time.Unix(100, 0)) {
+		t.Errorf("CreatedAt = %v, want Unix 100", time.Time(goDev.CreatedAt))
+	}
+	if len(goDev.UpdatedAt) != 1 || !time.Time(goDev.UpdatedAt[0]).Equal(time.Unix(200, 0)) {
+		t.Errorf("UpdatedAt = %v, want [Unix 200]", goDev.UpdatedAt)
+	}
+	if visited, ok := goDev.LastVisitedAt.Get(); !ok || !visited.Equal(time.Unix(500, 0)) {
+		t.Errorf("LastVisitedAt = (%v, %v), want (Unix 500, true)", visited, ok)
+	}
+	if len(goDev.Folders) != 1 || len(goDev.Folders[0]) != 1 || goDev.Folders[0][0].Name != "Bookmarks Toolbar" {
+		t.Errorf("Folders = %v, want [[Bookmarks Toolbar]]", goDev.Folders)
+	}
+
+	nested := entities[2]
+	if names := types.MapToSortedSlice(nested.Names); !slices.Equal(names, []string{"Page title"}) {
+		t.Errorf("Names = %v, want the page title as fallback", names)
+	}
+	if labels := types.MapToSortedSlice(nested.Labels); !slices.Equal(labels, []string{"Reading", "lang", "later"}) {
+		t.Errorf("Labels = %v, want [Reading lang later]", labels)
+	}
+	if len(nested.UpdatedAt) != 0 {
+		t.Errorf("UpdatedAt = %v, want none when lastModified equals dateAdded", nested.UpdatedAt)
+	}
+	if _, ok := nested.LastVisitedAt.Get(); ok {
+		t.Error("LastVisitedAt should be unset without a visit")
+	}
+	if len(nested.Folders) != 1 || len(nested.Folders[0]) != 2 || nested.Folders[0][1].Name != "Reading" {
+		t.Fatalf("Folders = %v, want [[Bookmarks Toolbar Reading]]", nested.Folders)
+	}
+	if reading := nested.Folders[0][1]; !reading.AddDate.Equal(time.Unix(50, 0)) {
+		t.Errorf("Reading AddDate = %v, want Unix 50", reading.AddDate)
+	}
+}
+
+func TestFirefoxParserRejectsOtherFiles(t *testing.T) {
+	p := &FirefoxParser{}
+	if _, err := p.Parse(strings.NewReader("not a database")); err == nil {
+		t.Error("expected error for a file that is not a places database")
+	}
+}
+
+func TestFirefoxParserRemovesTemporaryFiles(t *testing.T) {
+	// Firefox keeps places.sqlite in WAL mode, in which SQLite opens the
+	// copy alongside -wal and -shm files.
+	path := buildPlacesDatabase(t)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		t.Fatalf("enabling WAL: %v", err)
+	}
+	db.Close()
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := (&FirefoxParser{}).Parse(f); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("left behind in the temporary directory: %s", entry.Name())
+	}
+}
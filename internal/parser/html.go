@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -29,7 +30,7 @@ type pendingBookmark struct {
 
 func add(
 	coll *types.Collection,
-	folders []string,
+	folders []types.Folder,
 	pending pendingBookmark,
 ) error {
 	if pending.href == "" {
@@ -83,7 +84,7 @@ func add(
 	}
 
 	for _, folder := range folders {
-		labels[types.Label(folder)] = struct{}{}
+		labels[types.Label(folder.Name)] = struct{}{}
 	}
 
 	var shared types.Shared
@@ -125,6 +126,10 @@ func add(
 
 	entity.LastVisitedAt = lastVisitedAt
 
+	if len(folders) > 0 {
+		entity.Folders = []types.FolderPath{slices.Clone(folders)}
+	}
+
 	coll.Upsert(entity)
 
 	return nil
@@ -182,6 +187,32 @@ func handleAnchor(anchor *html.Node) pendingBookmark {
 	return ret
 }
 
+func parseUnixAttr(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(parsed, 0)
+}
+
+func handleFolder(heading *html.Node) types.Folder {
+	ret := types.Folder{Name: strings.TrimSpace(getTextContent(heading))}
+
+	for _, attr := range heading.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "add_date":
+			ret.AddDate = parseUnixAttr(attr.Val)
+		case "last_modified":
+			ret.LastModified = parseUnixAttr(attr.Val)
+		}
+	}
+
+	return ret
+}
+
 func parse(root *html.Node, coll *types.Collection) error {
 	type workItem struct {
 		node     *html.Node
@@ -190,7 +221,7 @@ func parse(root *html.Node, coll *types.Collection) error {
 
 	var (
 		worklist   []workItem
-		folders    []string
+		folders    []types.Folder
 		pending    pendingBookmark
 		hasPending bool
 	)
@@ -239,9 +270,9 @@ func parse(root *html.Node, coll *types.Collection) error {
 					pending = handleAnchor(c)
 					hasPending = true
 				case "h3":
-					folderName := strings.TrimSpace(getTextContent(c))
-					if folderName != "" {
-						folders = append(folders, folderName)
+					folder := handleFolder(c)
+					if folder.Name != "" {
+						folders = append(folders, folder)
 					}
 				}
 			}
@@ -84,3 +84,58 @@ func TestHTMLParserToreadTag(t *testing.T) {
 		}
 	})
 }
+
+func TestHTMLParserFolders(t *testing.T) {
+	const doc = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><H3 ADD_DATE="100" LAST_MODIFIED="200">Outer</H3>
+    <DL><p>
+        <DT><H3>Inner</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/nested" ADD_DATE="300">Nested</A>
+        </DL><p>
+        <DT><A HREF="https://example.com/shallow" ADD_DATE="300">Shallow</A>
+    </DL><p>
+    <DT><A HREF="https://example.com/top" ADD_DATE="300">Top</A>
+</DL><p>
+`
+
+	p := &HTMLParser{}
+	coll, err := p.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := map[string][]string{
+		"https://example.com/nested":  {"Outer", "Inner"},
+		"https://example.com/shallow": {"Outer"},
+		"https://example.com/top":     nil,
+	}
+
+	for e := range coll.Entities() {
+		wantPath, ok := want[e.URI.String()]
+		if !ok {
+			t.Fatalf("unexpected entity %s", e.URI)
+		}
+		if wantPath == nil {
+			if len(e.Folders) != 0 {
+				t.Errorf("%s: expected no folders, got %v", e.URI, e.Folders)
+			}
+			continue
+		}
+		if len(e.Folders) != 1 {
+			t.Fatalf("%s: expected one folder path, got %v", e.URI, e.Folders)
+		}
+		var names []string
+		for _, folder := range e.Folders[0] {
+			names = append(names, folder.Name)
+		}
+		if fmt.Sprint(names) != fmt.Sprint(wantPath) {
+			t.Errorf("%s: folder path %v, want %v", e.URI, names, wantPath)
+		}
+		outer := e.Folders[0][0]
+		if outer.AddDate.Unix() != 100 || outer.LastModified.Unix() != 200 {
+			t.Errorf("%s: Outer dates (%v, %v), want (100, 200)", e.URI, outer.AddDate.Unix(), outer.LastModified.Unix())
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package parser
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// JSONParser reads the versioned collection document written by
+// formatter.JSONFormatter. Pinboard's JSON export is handled separately by
+// the pinboard package.
+type JSONParser struct{}
+
+func (p *JSONParser) Parse(r io.Reader) (types.Collection, error) {
+	var coll types.Collection
+
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(&coll); err != nil {
+		return types.Collection{}, err
+	}
+
+	return coll, nil
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// JSONLParser reads the JSON Lines form of a collection written by
+// formatter.JSONLFormatter: one node per line, optionally preceded by a line
+// declaring the version. Blank lines are skipped, and a stream without the
+// version line, such as one filtered by grep, is accepted as is.
+type JSONLParser struct{}
+
+// jsonlHeader is the first line of a JSONL collection.
+type jsonlHeader struct {
+	Version *string `json:"version"`
+}
+
+func (p *JSONLParser) Parse(r io.Reader) (types.Collection, error) {
+	return types.Collect(p.Stream(r))
+}
+
+func (p *JSONLParser) Stream(r io.Reader) iter.Seq2[types.Node, error] {
+	return func(yield func(types.Node, error) bool) {
+		reader := bufio.NewReader(r)
+
+		var (
+			lineNum int
+			records int
+		)
+
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				yield(types.Node{}, err)
+				return
+			}
+			if len(line) == 0 && err != nil {
+				return
+			}
+			lineNum++
+
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			records++
+
+			var header jsonlHeader
+			if jerr := json.Unmarshal(line, &header); jerr != nil {
+				yield(types.Node{}, fmt.Errorf("line %d: %w", lineNum, jerr))
+				return
+			}
+			if header.Version != nil {
+				if records > 1 {
+					yield(types.Node{}, fmt.Errorf("line %d: version declared after the first node", lineNum))
+					return
+				}
+				if verr := types.CheckVersion(*header.Version); verr != nil {
+					yield(types.Node{}, fmt.Errorf("line %d: %w", lineNum, verr))
+					return
+				}
+				continue
+			}
+
+			var node types.Node
+			if jerr := json.Unmarshal(line, &node); jerr != nil {
+				yield(types.Node{}, fmt.Errorf("line %d: %w", lineNum, jerr))
+				return
+			}
+			if !yield(node, nil) {
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}
+}
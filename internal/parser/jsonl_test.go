@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJSONLParser(t *testing.T) {
+	const input = `{"version":"0.1.0"}
+{"id":0,"entity":{"uri":"https://go.dev/","createdAt":1609200000,"updatedAt":[],"names":["Go"],"labels":["go"],"toRead":true},"edges":[1,7]}
+
+{"id":1,"entity":{"uri":"https://go.dev/tour/","createdAt":1609200000,"updatedAt":[],"names":[],"labels":[]},"edges":[0]}
+{"id":2,"entity":{"uri":"https://go.dev/","createdAt":1609286400,"updatedAt":[],"names":["Golang"],"labels":[]},"edges":[]}
+`
+	p := &JSONLParser{}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if coll.Len() != 2 {
+		t.Fatalf("expected nodes sharing a URL to be absorbed into 2 entities, got %d", coll.Len())
+	}
+
+	var paths []string
+	for id, entity := range coll.Nodes() {
+		if entity.URI.Path != "/" {
+			continue
+		}
+		if len(entity.Names) != 2 || len(entity.UpdatedAt) != 1 {
+			t.Errorf("absorbed entity = %+v", entity)
+		}
+		for neighbor := range coll.Neighbors(id) {
+			for other, e := range coll.Nodes() {
+				if other == neighbor {
+					paths = append(paths, e.URI.Path)
+				}
+			}
+		}
+	}
+	if !slices.Equal(paths, []string{"/tour/"}) {
+		t.Errorf("neighbors = %v, want [/tour/] with the dangling edge dropped", paths)
+	}
+}
+
+func TestJSONLParserStream(t *testing.T) {
+	const input = `{"id":4,"entity":{"uri":"https://example.com/a","createdAt":0,"updatedAt":[],"names":[],"labels":[]},"edges":[5]}
+{"id":5,"entity":{"uri":"https://example.com/a","createdAt":0,"updatedAt":[],"names":[],"labels":[]},"edges":[4]}
+`
+	p := &JSONLParser{}
+	var ids []uint
+	for node, err := range p.Stream(strings.NewReader(input)) {
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		ids = append(ids, node.ID)
+	}
+	if !slices.Equal(ids, []uint{4, 5}) {
+		t.Errorf("streamed ids = %v, want [4 5] without absorbing", ids)
+	}
+}
+
+func TestJSONLParserErrors(t *testing.T) {
+	const node = `{"id":0,"entity":{"uri":"https://example.com/","createdAt":0,"updatedAt":[],"names":[],"labels":[]},"edges":[]}`
+
+	tests := []struct {
+		name  string
+		input string
+		line  string
+	}{
+		{"incompatible version", `{"version":"1.0.0"}`, "line 1"},
+		{"late version", node + "\n" + `{"version":"0.1.0"}`, "line 2"},
+		{"malformed line", node + "\n\n{", "line 3"},
+		{"duplicate id", node + "\n" + node, "duplicate node id 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &JSONLParser{}
+			_, err := p.Parse(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.line) {
+				t.Errorf("error %q does not mention %q", err, tt.line)
+			}
+		})
+	}
+}
@@ -13,7 +13,15 @@ import (
 	"github.com/yuin/goldmark/text"
 )
 
-type MarkdownParser struct{}
+// MarkdownParser reads the dated, labeled lists written by
+// formatter.MarkdownFormatter. A link in a nested list item is joined by an
+// edge to the link of the item enclosing it, or with ChildLinks linked as its
+// child. Link text keeps its backslash escapes, except that escaped brackets,
+// which the formatter writes for brackets in names, are unescaped.
+type MarkdownParser struct {
+	// ChildLinks records nesting as child-of links rather than edges.
+	ChildLinks bool
+}
 
 type parserState struct {
 	coll        types.Collection
@@ -21,6 +29,7 @@ type parserState struct {
 	labels      []string
 	maybeParent *types.Id
 	parents     []types.Id
+	childLinks  bool
 }
 
 func saveEntity(state *parserState, linkURL, linkTitle string) (types.Id, error) {
@@ -54,12 +63,18 @@ func saveEntity(state *parserState, linkURL, linkTitle string) (types.Id, error)
 
 	if len(state.parents) > 0 {
 		immediateParent := state.parents[len(state.parents)-1]
-		state.coll.AddEdges(nodeID, immediateParent)
+		if state.childLinks {
+			state.coll.Link(nodeID, immediateParent, types.LinkChildOf)
+		} else {
+			state.coll.AddEdges(nodeID, immediateParent)
+		}
 	}
 
 	return nodeID, nil
 }
 
+var linkTextUnescaper = strings.NewReplacer(`\[`, "[", `\]`, "]")
+
 func extractText(node ast.Node, content []byte) string {
 	var buf bytes.Buffer
 
@@ -114,6 +129,7 @@ func (p *MarkdownParser) Parse(r io.Reader) (types.Collection, error) {
 		labels:      []string{},
 		maybeParent: nil,
 		parents:     []types.Id{},
+		childLinks:  p.ChildLinks,
 	}
 
 	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
@@ -154,7 +170,7 @@ func (p *MarkdownParser) Parse(r io.Reader) (types.Collection, error) {
 		case *ast.Link:
 			if entering {
 				linkURL := string(node.Destination)
-				linkTitle := extractText(node, content)
+				linkTitle := linkTextUnescaper.Replace(extractText(node, content))
 
 				if linkURL != "" {
 					id, err := saveEntity(&state, linkURL, linkTitle)
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/opml"
+	"github.com/henrytill/hbt-go/internal/types"
+	"golang.org/x/net/html/charset"
+)
+
+// OPMLParser reads a feed reader's OPML subscription list. Each subscription
+// becomes an entity for its feed URL with IsFeed set, named by its text and
+// title. Outlines that group others are folders: they become labels, and
+// each entity records its folder path. Every component of an outline's
+// category paths becomes a label as well. Link outlines, as written by
+// OPMLFormatter for entities that are not feeds, become entities with IsFeed
+// set to false. A subscription's site URL has no counterpart in an entity and
+// is not read.
+type OPMLParser struct{}
+
+func opmlName(outline *opml.Outline) string {
+	if text := strings.TrimSpace(outline.Text); text != "" {
+		return text
+	}
+	return strings.TrimSpace(outline.Title)
+}
+
+func addOPMLOutline(
+	coll *types.Collection,
+	outline *opml.Outline,
+	href string,
+	isFeed bool,
+	folders []types.Folder,
+) error {
+	parsedURL, err := url.Parse(href)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL %s: %w", href, err)
+	}
+
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+	}
+
+	createdAt, ok := opml.ParseTime(strings.TrimSpace(outline.Created))
+	if !ok {
+		createdAt = time.Now()
+	}
+
+	names := make(map[types.Name]struct{})
+	for _, name := range []string{outline.Text, outline.Title} {
+		if name = strings.TrimSpace(name); name != "" {
+			names[types.Name(name)] = struct{}{}
+		}
+	}
+
+	labels := make(map[types.Label]struct{})
+	for category := range strings.SplitSeq(outline.Category, ",") {
+		for label := range strings.SplitSeq(category, "/") {
+			if label = strings.TrimSpace(label); label != "" {
+				labels[types.Label(label)] = struct{}{}
+			}
+		}
+	}
+
+	for _, folder := range folders {
+		labels[types.Label(folder.Name)] = struct{}{}
+	}
+
+	entity := types.Entity{
+		URI:       parsedURL,
+		CreatedAt: types.CreatedAt(createdAt),
+		UpdatedAt: []types.UpdatedAt{},
+		Names:     names,
+		Labels:    labels,
+		IsFeed:    types.NewIsFeed(isFeed),
+	}
+
+	if description := strings.TrimSpace(outline.Description); description != "" {
+		entity.Extended = []types.Extended{types.Extended(description)}
+	}
+
+	if len(folders) > 0 {
+		entity.Folders = []types.FolderPath{slices.Clone(folders)}
+	}
+
+	coll.Upsert(entity)
+
+	return nil
+}
+
+func walkOPMLOutlines(coll *types.Collection, outlines []opml.Outline, folders []types.Folder) error {
+	for i := range outlines {
+		outline := &outlines[i]
+
+		xmlURL := strings.TrimSpace(outline.XMLURL)
+		linkURL := strings.TrimSpace(outline.URL)
+
+		switch {
+		case xmlURL != "":
+			if err := addOPMLOutline(coll, outline, xmlURL, true, folders); err != nil {
+				return err
+			}
+		case strings.EqualFold(outline.Type, opml.TypeLink) && linkURL != "":
+			if err := addOPMLOutline(coll, outline, linkURL, false, folders); err != nil {
+				return err
+			}
+		}
+
+		if len(outline.Outlines) == 0 {
+			continue
+		}
+
+		path := folders
+		if name := opmlName(outline); name != "" && xmlURL == "" {
+			folder := types.Folder{Name: name}
+			if created, ok := opml.ParseTime(strings.TrimSpace(outline.Created)); ok {
+				folder.AddDate = created
+			}
+			path = append(slices.Clip(folders), folder)
+		}
+		if err := walkOPMLOutlines(coll, outline.Outlines, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *OPMLParser) Parse(r io.Reader) (types.Collection, error) {
+	var doc opml.Document
+
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&doc); err != nil {
+		return types.Collection{}, fmt.Errorf("failed to parse OPML: %w", err)
+	}
+
+	coll := types.NewCollection()
+	if err := walkOPMLOutlines(&coll, doc.Body.Outlines, nil); err != nil {
+		return types.Collection{}, err
+	}
+
+	return coll, nil
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+const opmlSubscriptions = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<opml version="1.0">
+  <head>
+    <title>Subscriptions</title>
+  </head>
+  <body>
+    <outline text="Go Blog" title="The Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog/" created="Tue, 29 Dec 2020 00:00:00 +0000"/>
+    <outline text="Tech" title="Tech">
+      <outline text="LWN" type="rss" xmlUrl="https://lwn.net/headlines/rss" category="/News/Linux,kernel" description="Linux news"/>
+      <outline text="Caf&#233;" type="link" url="https://example.com/cafe"/>
+    </outline>
+    <outline text="Empty"/>
+  </body>
+</opml>
+`
+
+func TestOPMLParser(t *testing.T) {
+	p := &OPMLParser{}
+	coll, err := p.Parse(strings.NewReader(opmlSubscriptions))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	entities := slices.Collect(coll.Entities())
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 entities, got %d", len(entities))
+	}
+
+	blog := entities[0]
+	if blog.URI.String() != "https://go.dev/blog/feed.atom" {
+		t.Errorf("URI = %s, want the feed URL", blog.URI)
+	}
+	if isFeed, ok := blog.IsFeed.Get(); !ok || !isFeed {
+		t.Errorf("IsFeed = (%v, %v), want (true, true)", isFeed, ok)
+	}
+	wantNames := []string{"Go Blog", "The Go Blog"}
+	if names := types.MapToSortedSlice(blog.Names); !slices.Equal(names, wantNames) {
+		t.Errorf("Names = %v, want %v", names, wantNames)
+	}
+	created := time.Date(2020, 12, 29, 0, 0, 0, 0, time.UTC)
+	if !time.Time(blog.CreatedAt).Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", time.Time(blog.CreatedAt), created)
+	}
+	if len(blog.Labels) != 0 || len(blog.Folders) != 0 {
+		t.Errorf("top-level outline: Labels = %v, Folders = %v, want none", blog.Labels, blog.Folders)
+	}
+
+	lwn := entities[1]
+	wantLabels := []string{"Linux", "News", "Tech", "kernel"}
+	if labels := types.MapToSortedSlice(lwn.Labels); !slices.Equal(labels, wantLabels) {
+		t.Errorf("Labels = %v, want %v", labels, wantLabels)
+	}
+	if len(lwn.Folders) != 1 || len(lwn.Folders[0]) != 1 || lwn.Folders[0][0].Name != "Tech" {
+		t.Errorf("Folders = %v, want [[Tech]]", lwn.Folders)
+	}
+	if len(lwn.Extended) != 1 || lwn.Extended[0] != "Linux news" {
+		t.Errorf("Extended = %v, want [Linux news]", lwn.Extended)
+	}
+
+	cafe := entities[2]
+	if isFeed, ok := cafe.IsFeed.Get(); !ok || isFeed {
+		t.Errorf("link outline: IsFeed = (%v, %v), want (false, true)", isFeed, ok)
+	}
+	if _, ok := cafe.Names[types.Name("Café")]; !ok {
+		t.Errorf("Names = %v, want [Café]", cafe.Names)
+	}
+}
+
+func TestOPMLParserRejectsMalformedInput(t *testing.T) {
+	p := &OPMLParser{}
+	if _, err := p.Parse(strings.NewReader("<opml><body><outline></body>")); err == nil {
+		t.Error("expected error for malformed OPML")
+	}
+}
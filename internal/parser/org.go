@@ -0,0 +1,397 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// OrgParser reads Org files in the model of MarkdownParser. A top-level
+// headline holding a date, as an Org timestamp or as "January 2, 2006", dates
+// the entities below it. Deeper headlines are labels, one per level, and the
+// tags of every enclosing headline are labels too. Links, written
+// [[url][description]] or [[url]], become entities named by their
+// description, and a link in a nested list item is joined by an edge to the
+// link of the item enclosing it, or with ChildLinks linked as its child. A
+// headline holding a link is an entity rather than a label.
+//
+// A property drawer following a link headline or list item sets fields of
+// the entity it names: CREATED, UPDATED, and LAST_VISITED hold timestamps;
+// TOREAD, SHARED, and FEED hold t or nil; EXTENDED and NAME add descriptions
+// and names, and may be repeated with a "+" suffix. Other drawers, blocks,
+// and keyword lines are skipped. Org timestamps carry no zone and are read
+// as UTC.
+type OrgParser struct {
+	// ChildLinks records nesting as child-of links rather than edges.
+	ChildLinks bool
+}
+
+var (
+	orgHeadline = regexp.MustCompile(`^(\*+)\s+(.*?)\s*$`)
+	orgTags     = regexp.MustCompile(`\s+:((?:[\p{L}\p{N}_@#%]+:)+)$`)
+	orgItem     = regexp.MustCompile(`^(\s*)(?:[-+]|\d+[.)])\s+(.*)$`)
+	orgLink     = regexp.MustCompile(`\[\[((?:[^\[\]\\]|\\.)+)\](?:\[((?:[^\[\]]|\[[^\[\]]*\])*)\])?\]`)
+	orgDrawer   = regexp.MustCompile(`^\s*:([\w-]+):\s*$`)
+	orgProperty = regexp.MustCompile(`^\s*:([\w-]+?)(\+)?:(?:\s+(.*?))?\s*$`)
+	orgBlock    = regexp.MustCompile(`(?i)^\s*#\+begin_(\w+)`)
+	orgTime     = regexp.MustCompile(`^[\[<](\d{4}-\d{2}-\d{2})(?:\s+[^\s\d\]>]+)?(?:\s+(\d{1,2}:\d{2}))?[\]>]$`)
+	orgTimes    = regexp.MustCompile(`[\[<][^\[\]<>]*[\]>]`)
+)
+
+// orgPathEscapes undoes the backslash escapes Org writes in link paths.
+var orgPathEscapes = strings.NewReplacer(`\[`, "[", `\]`, "]", `\\`, `\`)
+
+// parseOrgTime parses an Org timestamp such as "[2020-12-29 Tue 10:00]".
+func parseOrgTime(s string) (time.Time, bool) {
+	m := orgTime.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}, false
+	}
+	layout, value := "2006-01-02", m[1]
+	if m[2] != "" {
+		layout, value = "2006-01-02 15:04", m[1]+" "+m[2]
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func parseOrgFlag(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "t", "yes", "true":
+		return true, nil
+	case "nil", "no", "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid flag %q", s)
+	}
+}
+
+type orgLinkRef struct {
+	url         string
+	description string
+}
+
+func findOrgLinks(text string) []orgLinkRef {
+	var links []orgLinkRef
+	for _, m := range orgLink.FindAllStringSubmatch(text, -1) {
+		path := orgPathEscapes.Replace(m[1])
+		// Links to headlines and targets within the file have no scheme.
+		if parsed, err := url.Parse(path); err != nil || parsed.Scheme == "" {
+			continue
+		}
+		links = append(links, orgLinkRef{url: path, description: strings.TrimSpace(m[2])})
+	}
+	return links
+}
+
+// orgPending holds the entities of a line until any property drawer
+// following it has been read.
+type orgPending struct {
+	entities []types.Entity
+	parent   *types.Id
+	indent   int
+	item     bool
+}
+
+type orgListItem struct {
+	indent int
+	id     *types.Id
+}
+
+type orgState struct {
+	coll        types.Collection
+	currentDate time.Time
+	labels      []string
+	tags        [][]string
+	items       []orgListItem
+	pending     *orgPending
+	childLinks  bool
+}
+
+func (s *orgState) currentLabels() map[types.Label]struct{} {
+	labels := make(map[types.Label]struct{})
+	for _, label := range s.labels {
+		if label = strings.TrimSpace(label); label != "" {
+			labels[types.Label(label)] = struct{}{}
+		}
+	}
+	for _, tags := range s.tags {
+		for _, tag := range tags {
+			labels[types.Label(tag)] = struct{}{}
+		}
+	}
+	return labels
+}
+
+func (s *orgState) newEntity(link orgLinkRef) (types.Entity, error) {
+	parsedURL, err := url.Parse(link.url)
+	if err != nil {
+		return types.Entity{}, fmt.Errorf("failed to parse URL %s: %w", link.url, err)
+	}
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+	}
+
+	entity := types.Entity{
+		URI:       parsedURL,
+		CreatedAt: types.CreatedAt(s.currentDate),
+		UpdatedAt: []types.UpdatedAt{},
+		Names:     make(map[types.Name]struct{}),
+		Labels:    s.currentLabels(),
+	}
+
+	if link.description != "" {
+		entity.Names[types.Name(link.description)] = struct{}{}
+	}
+
+	return entity, nil
+}
+
+// flush saves the pending entities, joining them to their parent item, and
+// records a list item as a possible parent of the items nested in it.
+func (s *orgState) flush() {
+	p := s.pending
+	if p == nil {
+		return
+	}
+	s.pending = nil
+
+	var last *types.Id
+	for _, entity := range p.entities {
+		id := s.coll.Upsert(entity)
+		if p.parent != nil {
+			if s.childLinks {
+				s.coll.Link(id, *p.parent, types.LinkChildOf)
+			} else {
+				s.coll.AddEdges(id, *p.parent)
+			}
+		}
+		last = &id
+	}
+
+	if p.item {
+		s.items = append(s.items, orgListItem{indent: p.indent, id: last})
+	}
+}
+
+// applyProperty sets the field named by key on the pending entities.
+func (s *orgState) applyProperty(key string, value string) error {
+	if s.pending == nil || value == "" {
+		return nil
+	}
+
+	for i := range s.pending.entities {
+		entity := &s.pending.entities[i]
+		switch strings.ToUpper(key) {
+		case "CREATED":
+			t, ok := parseOrgTime(value)
+			if !ok {
+				return fmt.Errorf("invalid timestamp %q", value)
+			}
+			entity.CreatedAt = types.CreatedAt(t)
+		case "UPDATED":
+			for _, stamp := range orgTimes.FindAllString(value, -1) {
+				t, ok := parseOrgTime(stamp)
+				if !ok {
+					return fmt.Errorf("invalid timestamp %q", stamp)
+				}
+				entity.UpdatedAt = append(entity.UpdatedAt, types.UpdatedAt(t))
+			}
+		case "LAST_VISITED":
+			t, ok := parseOrgTime(value)
+			if !ok {
+				return fmt.Errorf("invalid timestamp %q", value)
+			}
+			entity.LastVisitedAt = types.NewLastVisitedAt(t)
+		case "TOREAD", "SHARED", "FEED":
+			b, err := parseOrgFlag(value)
+			if err != nil {
+				return err
+			}
+			switch strings.ToUpper(key) {
+			case "TOREAD":
+				entity.ToRead = types.NewToRead(b)
+			case "SHARED":
+				entity.Shared = types.NewShared(b)
+			case "FEED":
+				entity.IsFeed = types.NewIsFeed(b)
+			}
+		case "EXTENDED":
+			entity.Extended = append(entity.Extended, types.Extended(value))
+		case "NAME":
+			entity.Names[types.Name(value)] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+func (s *orgState) headline(level int, title string) error {
+	s.flush()
+	s.items = s.items[:0]
+
+	var tags []string
+	if m := orgTags.FindStringSubmatchIndex(title); m != nil {
+		tags = strings.Split(strings.Trim(title[m[2]:m[3]], ":"), ":")
+		title = strings.TrimSpace(title[:m[0]])
+	}
+
+	for len(s.tags) < level {
+		s.tags = append(s.tags, nil)
+	}
+	s.tags = s.tags[:level]
+	s.tags[level-1] = tags
+
+	depth := level - 2
+	if level == 1 {
+		if t, ok := parseOrgTime(title); ok {
+			s.currentDate = t
+		} else if t, err := time.Parse("January 2, 2006", title); err == nil {
+			s.currentDate = t
+		}
+		s.labels = s.labels[:0]
+	} else if depth < len(s.labels) {
+		s.labels = s.labels[:depth]
+	}
+
+	links := findOrgLinks(title)
+	if len(links) == 0 {
+		if level > 1 {
+			for len(s.labels) <= depth {
+				s.labels = append(s.labels, "")
+			}
+			s.labels[depth] = title
+		}
+		return nil
+	}
+
+	pending := &orgPending{}
+	for _, link := range links {
+		entity, err := s.newEntity(link)
+		if err != nil {
+			return err
+		}
+		pending.entities = append(pending.entities, entity)
+	}
+	s.pending = pending
+
+	return nil
+}
+
+func (s *orgState) item(indent int, text string) error {
+	s.flush()
+
+	for len(s.items) > 0 && s.items[len(s.items)-1].indent >= indent {
+		s.items = s.items[:len(s.items)-1]
+	}
+
+	pending := &orgPending{indent: indent, item: true}
+	for i := len(s.items) - 1; i >= 0; i-- {
+		if s.items[i].id != nil {
+			pending.parent = s.items[i].id
+			break
+		}
+	}
+
+	for _, link := range findOrgLinks(text) {
+		entity, err := s.newEntity(link)
+		if err != nil {
+			return err
+		}
+		pending.entities = append(pending.entities, entity)
+	}
+	s.pending = pending
+
+	return nil
+}
+
+func (p *OrgParser) Parse(r io.Reader) (types.Collection, error) {
+	state := orgState{coll: types.NewCollection(), childLinks: p.ChildLinks}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	var (
+		lineNum    int
+		drawer     string
+		block      string
+		properties bool
+	)
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if block != "" {
+			if strings.EqualFold(trimmed, "#+end_"+block) {
+				block = ""
+			}
+			continue
+		}
+
+		if drawer != "" {
+			if strings.EqualFold(trimmed, ":END:") {
+				drawer = ""
+				properties = false
+				continue
+			}
+			if properties {
+				if m := orgProperty.FindStringSubmatch(line); m != nil {
+					if err := state.applyProperty(m[1], m[3]); err != nil {
+						return types.Collection{}, fmt.Errorf("line %d: %s: %w", lineNum, m[1], err)
+					}
+				}
+			}
+			continue
+		}
+
+		if m := orgDrawer.FindStringSubmatch(line); m != nil && !strings.EqualFold(m[1], "END") {
+			drawer = m[1]
+			// Property drawers apply to the line just read; the pending
+			// entities are saved once the drawer ends.
+			properties = strings.EqualFold(drawer, "PROPERTIES")
+			continue
+		}
+
+		if m := orgBlock.FindStringSubmatch(line); m != nil {
+			block = strings.ToLower(m[1])
+			state.flush()
+			continue
+		}
+
+		if m := orgHeadline.FindStringSubmatch(line); m != nil {
+			if err := state.headline(len(m[1]), m[2]); err != nil {
+				return types.Collection{}, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			continue
+		}
+
+		if m := orgItem.FindStringSubmatch(line); m != nil {
+			indent := len(strings.ReplaceAll(m[1], "\t", "        "))
+			if err := state.item(indent, m[2]); err != nil {
+				return types.Collection{}, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			continue
+		}
+
+		state.flush()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return types.Collection{}, err
+	}
+
+	state.flush()
+
+	return state.coll, nil
+}
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+const orgInput = `#+TITLE: Links
+
+* [2023-11-15 Wed]                                                 :daily:
+
+- [[https://example.com/plain][Plain]]
+
+** Programming                                                       :dev:
+*** Go
+- [[https://go.dev/][Go]]
+  :PROPERTIES:
+  :CREATED: [2023-11-15 Wed 10:30]
+  :TOREAD: t
+  :EXTENDED: The Go homepage
+  :EXTENDED+: Downloads and docs
+  :NAME: Golang
+  :END:
+  - [[https://go.dev/tour/][Tour]]
+    - [[https://go.dev/tour/welcome/1]]
+  - [[https://go.dev/ref/spec][Spec]] and [[*Notes][notes]]
+
+#+BEGIN_SRC sh
+- [[https://example.com/in-block][Not a link]]
+#+END_SRC
+
+** [[https://example.com/read][Reading]]                           :later:
+:PROPERTIES:
+:SHARED: nil
+:LAST_VISITED: <2023-11-20 Mon 08:00>
+:END:
+
+* November 16, 2023
+:LOGBOOK:
+- Note taken on [2023-11-16 Thu 09:00]
+:END:
+- [[https://go.dev/][Go, again]]
+`
+
+func TestOrgParser(t *testing.T) {
+	p := &OrgParser{}
+	coll, err := p.Parse(strings.NewReader(orgInput))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	entities := make(map[string]types.Entity)
+	for e := range coll.Entities() {
+		entities[e.URI.String()] = e
+	}
+	if len(entities) != 6 {
+		t.Fatalf("expected 6 entities, got %d: %v", len(entities), slices.Sorted(maps.Keys(entities)))
+	}
+
+	day := time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC)
+
+	plain := entities["https://example.com/plain"]
+	if !time.Time(plain.CreatedAt).Equal(day) {
+		t.Errorf("CreatedAt = %v, want %v", time.Time(plain.CreatedAt), day)
+	}
+	if labels := types.MapToSortedSlice(plain.Labels); !slices.Equal(labels, []string{"daily"}) {
+		t.Errorf("Labels = %v, want [daily]", labels)
+	}
+
+	goDev := entities["https://go.dev/"]
+	wantLabels := []string{"Go", "Programming", "daily", "dev"}
+	if labels := types.MapToSortedSlice(goDev.Labels); !slices.Equal(labels, wantLabels) {
+		t.Errorf("Labels = %v, want %v", labels, wantLabels)
+	}
+	if !time.Time(goDev.CreatedAt).Equal(day.Add(10*time.Hour + 30*time.Minute)) {
+		t.Errorf("CreatedAt = %v, want 10:30", time.Time(goDev.CreatedAt))
+	}
+	if toRead, ok := goDev.ToRead.Get(); !ok || !toRead {
+		t.Errorf("ToRead = (%v, %v), want (true, true)", toRead, ok)
+	}
+	wantNames := []string{"Go", "Go, again", "Golang"}
+	if names := types.MapToSortedSlice(goDev.Names); !slices.Equal(names, wantNames) {
+		t.Errorf("Names = %v, want %v", names, wantNames)
+	}
+	wantExtended := []types.Extended{"The Go homepage", "Downloads and docs"}
+	if !slices.Equal(goDev.Extended, wantExtended) {
+		t.Errorf("Extended = %v, want %v", goDev.Extended, wantExtended)
+	}
+
+	for _, child := range []string{"https://go.dev/tour/", "https://go.dev/ref/spec"} {
+		if _, ok := entities[child]; !ok {
+			t.Errorf("missing entity %s", child)
+		}
+	}
+
+	read := entities["https://example.com/read"]
+	if labels := types.MapToSortedSlice(read.Labels); !slices.Equal(labels, []string{"daily", "later"}) {
+		t.Errorf("link headline Labels = %v, want [daily later]", labels)
+	}
+	if shared, ok := read.Shared.Get(); !ok || shared {
+		t.Errorf("Shared = (%v, %v), want (false, true)", shared, ok)
+	}
+	if visited, ok := read.LastVisitedAt.Get(); !ok || !visited.Equal(time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("LastVisitedAt = (%v, %v)", visited, ok)
+	}
+
+	if _, ok := entities["https://example.com/in-block"]; ok {
+		t.Error("links inside blocks should be skipped")
+	}
+}
+
+func TestOrgParserEdges(t *testing.T) {
+	const input = `- [[https://example.com/a][A]]
+  - [[https://example.com/b][B]]
+    - [[https://example.com/c][C]]
+  - [[https://example.com/d][D]]
+- [[https://example.com/e][E]]
+`
+	p := &OrgParser{}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	ids := make(map[string]types.Id)
+	for id, e := range coll.Nodes() {
+		ids[e.URI.Path] = id
+	}
+
+	neighbors := func(path string) []string {
+		var got []string
+		for id := range coll.Neighbors(ids[path]) {
+			for p, other := range ids {
+				if other == id {
+					got = append(got, p)
+				}
+			}
+		}
+		slices.Sort(got)
+		return got
+	}
+
+	tests := map[string][]string{
+		"/a": {"/b", "/d"},
+		"/b": {"/a", "/c"},
+		"/c": {"/b"},
+		"/d": {"/a"},
+		"/e": nil,
+	}
+	for path, want := range tests {
+		if got := neighbors(path); !slices.Equal(got, want) {
+			t.Errorf("neighbors of %s = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestOrgParserLinks(t *testing.T) {
+	const input = `- [[https://example.com/a][A]]
+  - [[https://example.com/b][B]]
+    - [[https://example.com/c][C]]
+  - [[https://example.com/d][D]]
+- [[https://example.com/e][E]]
+`
+	p := &OrgParser{ChildLinks: true}
+	coll, err := p.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	ids := make(map[string]types.Id)
+	paths := make(map[types.Id]string)
+	for id, e := range coll.Nodes() {
+		ids[e.URI.Path] = id
+		paths[id] = e.URI.Path
+	}
+
+	pathsOf := func(ids []types.Id) []string {
+		var got []string
+		for _, id := range ids {
+			got = append(got, paths[id])
+		}
+		return got
+	}
+
+	tests := map[string]struct{ parents, children []string }{
+		"/a": {nil, []string{"/b", "/d"}},
+		"/b": {[]string{"/a"}, []string{"/c"}},
+		"/c": {[]string{"/b"}, nil},
+		"/d": {[]string{"/a"}, nil},
+		"/e": {nil, nil},
+	}
+	for path, want := range tests {
+		if got := pathsOf(coll.Parents(ids[path])); !slices.Equal(got, want.parents) {
+			t.Errorf("parents of %s = %v, want %v", path, got, want.parents)
+		}
+		if got := pathsOf(coll.Children(ids[path])); !slices.Equal(got, want.children) {
+			t.Errorf("children of %s = %v, want %v", path, got, want.children)
+		}
+		for range coll.Neighbors(ids[path]) {
+			t.Errorf("%s has an undirected edge", path)
+		}
+	}
+}
+
+func TestOrgParserInvalidProperty(t *testing.T) {
+	const input = `- [[https://example.com/][Example]]
+  :PROPERTIES:
+  :CREATED: yesterday
+  :END:
+`
+	p := &OrgParser{}
+	if _, err := p.Parse(strings.NewReader(input)); err == nil {
+		t.Error("expected error for an invalid timestamp")
+	}
+}
@@ -0,0 +1,25 @@
+package parser
+
+import (
+	"io"
+
+	"github.com/goccy/go-yaml"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// YAMLParser reads the versioned collection document written by
+// formatter.YAMLFormatter. Version compatibility, the declared length, and
+// edge and link ranges are checked while decoding; errors in a node are
+// reported with its index.
+type YAMLParser struct{}
+
+func (p *YAMLParser) Parse(r io.Reader) (types.Collection, error) {
+	var coll types.Collection
+
+	decoder := yaml.NewDecoder(r)
+	if err := decoder.Decode(&coll); err != nil {
+		return types.Collection{}, err
+	}
+
+	return coll, nil
+}
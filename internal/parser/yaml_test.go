@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYAMLParser(t *testing.T) {
+	const doc = `version: 0.1.0
+length: 2
+value:
+  - id: 0
+    entity:
+      uri: https://example.com/parent
+      createdAt: 100
+      updatedAt: []
+      names:
+        - Parent
+      labels:
+        - a
+      shared: true
+    edges:
+      - 1
+  - id: 1
+    entity:
+      uri: https://example.com/child
+      createdAt: 200
+      updatedAt: []
+      names: []
+      labels: []
+    edges:
+      - 0
+`
+
+	p := &YAMLParser{}
+	coll, err := p.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if coll.Len() != 2 {
+		t.Fatalf("expected 2 entities, got %d", coll.Len())
+	}
+	for e := range coll.Entities() {
+		if e.URI.String() != "https://example.com/parent" {
+			t.Errorf("first entity: got %s", e.URI)
+		}
+		if shared, ok := e.Shared.Get(); !ok || !shared {
+			t.Errorf("shared: got (%v, %v), want (true, true)", shared, ok)
+		}
+		break
+	}
+}
+
+func TestYAMLParserErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{
+			name:    "incompatible version",
+			doc:     "version: 1.0.0\nlength: 0\nvalue: []\n",
+			wantErr: "incompatible version",
+		},
+		{
+			name:    "invalid version",
+			doc:     "version: banana\nlength: 0\nvalue: []\n",
+			wantErr: "invalid version",
+		},
+		{
+			name:    "length mismatch",
+			doc:     "version: 0.1.0\nlength: 3\nvalue: []\n",
+			wantErr: "length mismatch",
+		},
+		{
+			name: "edge out of range",
+			doc: `version: 0.1.0
+length: 1
+value:
+  - id: 0
+    entity:
+      uri: https://example.com/
+      createdAt: 0
+    edges: [4]
+`,
+			wantErr: "node 0: edge 4 out of range",
+		},
+		{
+			name: "missing uri",
+			doc: `version: 0.1.0
+length: 2
+value:
+  - id: 0
+    entity:
+      uri: https://example.com/
+      createdAt: 0
+  - id: 1
+    entity:
+      createdAt: 0
+`,
+			wantErr: "node 1: missing uri",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &YAMLParser{}
+			_, err := p.Parse(strings.NewReader(tt.doc))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package pinboard
 
+import "encoding/xml"
+
 type Post struct {
 	Href        string `xml:"href,attr"        json:"href"`
 	Time        string `xml:"time,attr"        json:"time"`
@@ -13,6 +15,7 @@ type Post struct {
 }
 
 type Posts struct {
-	User  string `xml:"user,attr"`
-	Posts []Post `xml:"post"`
+	XMLName xml.Name `xml:"posts"`
+	User    string   `xml:"user,attr"`
+	Posts   []Post   `xml:"post"`
 }
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"github.com/henrytill/hbt-go/internal/belnap"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// Column evaluates expr over all of coll at once. The i-th value of the
+// result is the value of expr on the i-th entity in insertion order. Each
+// predicate fills a column with one test per entity, and the connectives
+// then combine whole columns with the bulk operations of belnap.Vec.
+func Column(coll *types.Collection, expr Expr) belnap.Vec {
+	nodes := make([]types.Node, 0, coll.Len())
+	for node := range coll.Stream() {
+		nodes = append(nodes, node)
+	}
+	return expr.column(nodes)
+}
+
+// Result partitions the entities of a collection by the value an expression
+// takes on them. Each list is in insertion order.
+type Result struct {
+	// Matches holds the entities the expression is True for.
+	Matches []types.Id
+	// NotMatches holds the entities it is False for.
+	NotMatches []types.Id
+	// Unknown holds the entities it cannot be decided for, because an
+	// attribute it depends on is unset.
+	Unknown []types.Id
+	// Contradicted holds the entities it is both True and False for, because
+	// an attribute it depends on is contradicted.
+	Contradicted []types.Id
+}
+
+// Evaluate evaluates expr over coll and partitions its entities by the
+// outcome.
+func Evaluate(coll *types.Collection, expr Expr) Result {
+	values := Column(coll, expr)
+
+	var r Result
+	i := 0
+	for id := range coll.Nodes() {
+		v, _ := values.Get(i)
+		switch v {
+		case belnap.True:
+			r.Matches = append(r.Matches, id)
+		case belnap.False:
+			r.NotMatches = append(r.NotMatches, id)
+		case belnap.Unknown:
+			r.Unknown = append(r.Unknown, id)
+		case belnap.Both:
+			r.Contradicted = append(r.Contradicted, id)
+		}
+		i++
+	}
+	return r
+}
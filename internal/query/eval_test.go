@@ -0,0 +1,119 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/belnap"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+func TestEvaluate(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	coll := types.NewCollection()
+
+	flags := []struct {
+		shared, toRead *bool
+	}{
+		{ptr(true), ptr(false)}, // True and not False: match
+		{ptr(true), ptr(true)},  // not True: no match
+		{ptr(false), nil},       // False and anything: no match
+		{ptr(true), nil},        // True and not Unknown: unknown
+		{nil, ptr(false)},       // Unknown and True: unknown
+	}
+	var ids []types.Id
+	for i, f := range flags {
+		entity := makeNode("https://example.com/"+string(rune('a'+i)), day).Entity
+		if f.shared != nil {
+			entity.Shared = types.NewShared(*f.shared)
+		}
+		if f.toRead != nil {
+			entity.ToRead = types.NewToRead(*f.toRead)
+		}
+		ids = append(ids, coll.Upsert(entity))
+	}
+
+	expr, err := parse("shared and not toread", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Evaluate(&coll, expr)
+	check := func(name string, got []types.Id, want ...types.Id) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s = %d entities, want %d", name, len(got), len(want))
+			return
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s[%d] differs", name, i)
+			}
+		}
+	}
+	check("Matches", r.Matches, ids[0])
+	check("NotMatches", r.NotMatches, ids[1], ids[2])
+	check("Unknown", r.Unknown, ids[3], ids[4])
+	check("Contradicted", r.Contradicted)
+}
+
+func TestColumnAgreesWithEval(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	coll := types.NewCollection()
+	a := makeNode("https://example.com/a", day, "go").Entity
+	a.ToRead = types.NewToRead(true)
+	b := makeNode("https://go.dev/b", day.AddDate(0, 3, 0)).Entity
+	b.LastVisitedAt = types.NewLastVisitedAt(day)
+	c := makeNode("https://example.com/c", day, "go").Entity
+	aID := coll.Upsert(a)
+	bID := coll.Upsert(b)
+	coll.Upsert(c)
+	coll.AddEdges(aID, bID)
+
+	queries := []string{
+		"toread or label:go",
+		"not toread and edges",
+		"visited>=2023-12-01 or host:go.dev",
+		"!(shared || feed) and created<2024-02-01",
+	}
+	for _, q := range queries {
+		expr, err := parse(q, now)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", q, err)
+		}
+		column := Column(&coll, expr)
+		for node := range coll.Stream() {
+			got, _ := column.Get(int(node.ID))
+			if want := expr.Eval(node); got != want {
+				t.Errorf("%q on %s: column %v, Eval %v", q, node.Entity.URI, got, want)
+			}
+		}
+	}
+}
+
+func TestEvalUnknownPropagates(t *testing.T) {
+	node := makeNode("https://example.com/", now)
+
+	tests := map[string]belnap.Value{
+		"toread":                belnap.Unknown,
+		"not toread":            belnap.Unknown,
+		"toread and label:none": belnap.False,
+		"toread or edges":       belnap.Unknown,
+		"toread or not edges":   belnap.True,
+		"visited>=2020-01-01":   belnap.Unknown,
+		"updated>=2020-01-01":   belnap.False,
+	}
+	for q, want := range tests {
+		expr, err := parse(q, now)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", q, err)
+		}
+		if got := expr.Eval(node); got != want {
+			t.Errorf("%q = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,306 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/henrytill/hbt-go/internal/belnap"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// Parse parses a filter expression. Ages in time predicates are taken
+// relative to the time Parse is called.
+func Parse(s string) (Expr, error) {
+	return parse(s, time.Now())
+}
+
+func parse(s string, now time.Time) (Expr, error) {
+	p := &parser{src: s, now: now}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return expr, nil
+}
+
+type parser struct {
+	src string
+	pos int
+	now time.Time
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("query: at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords. A keyword must not run on into a longer word.
+func (p *parser) accept(tokens ...string) bool {
+	p.skipSpace()
+	for _, token := range tokens {
+		if !strings.HasPrefix(p.src[p.pos:], token) {
+			continue
+		}
+		end := p.pos + len(token)
+		if isWordByte(token[0]) && end < len(p.src) && isWordByte(p.src[end]) {
+			continue
+		}
+		p.pos = end
+		return true
+	}
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or", "||") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = Or{x, y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and", "&&") {
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = And{x, y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.accept("not", "!") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+
+	if p.accept("(") {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("missing )")
+		}
+		return x, nil
+	}
+
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (Expr, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && isWordByte(p.src[p.pos]) {
+		p.pos++
+	}
+	field := strings.ToLower(p.src[start:p.pos])
+	switch field {
+	case "":
+		if p.pos == len(p.src) {
+			return nil, p.errorf("unexpected end of query")
+		}
+		return nil, p.errorf("expected a predicate, found %q", p.src[p.pos:])
+	case "and", "or":
+		p.pos = start
+		return nil, p.errorf("expected a predicate, found %s", field)
+	}
+
+	switch field {
+	case "toread":
+		return flagPredicate(field, func(e types.Entity) belnap.Value { return e.ToRead.Value() }), nil
+	case "shared":
+		return flagPredicate(field, func(e types.Entity) belnap.Value { return e.Shared.Value() }), nil
+	case "feed":
+		return flagPredicate(field, func(e types.Entity) belnap.Value { return e.IsFeed.Value() }), nil
+	case "edges":
+		return hasEdges(), nil
+	}
+
+	var op string
+	for _, candidate := range []string{"<=", ">=", ":", "~", "<", ">"} {
+		if strings.HasPrefix(p.src[p.pos:], candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		p.pos = start
+		return nil, p.errorf("expected an operator after %s", field)
+	}
+	p.pos += len(op)
+
+	valuePos := p.pos
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	pred, err := p.predicate(field, op, value)
+	if err != nil {
+		p.pos = valuePos
+		return nil, p.errorf("%v", err)
+	}
+	return pred, nil
+}
+
+// parseValue reads a double-quoted string or a bare word, which runs to the
+// next space or parenthesis.
+func (p *parser) parseValue() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], `"`) {
+		quoted, err := strconv.QuotedPrefix(p.src[p.pos:])
+		if err != nil {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos += len(quoted)
+		return strconv.Unquote(quoted)
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && !unicode.IsSpace(rune(p.src[p.pos])) && p.src[p.pos] != '(' && p.src[p.pos] != ')' {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("missing value")
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *parser) predicate(field, op, value string) (Expr, error) {
+	switch field {
+	case "label", "tag":
+		switch op {
+		case ":":
+			return labelIs(value), nil
+		case "~":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			return anyMatches("label", re, func(e types.Entity) map[types.Label]struct{} { return e.Labels }), nil
+		}
+	case "name", "title":
+		switch op {
+		case ":":
+			return nameContains(value), nil
+		case "~":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			return anyMatches("name", re, func(e types.Entity) map[types.Name]struct{} { return e.Names }), nil
+		}
+	case "url":
+		switch op {
+		case ":":
+			return urlMatches(op, value, func(s string) bool { return strings.Contains(s, value) }), nil
+		case "~":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			return urlMatches(op, value, re.MatchString), nil
+		}
+	case "host", "domain":
+		if op == ":" {
+			return hostIs(value), nil
+		}
+	case "source":
+		if op == ":" {
+			return sourceContains(value), nil
+		}
+	case "link":
+		if op == ":" {
+			kind, err := types.ParseLinkKind(value)
+			if err != nil {
+				return nil, err
+			}
+			return hasLink(kind), nil
+		}
+	case "created", "updated", "visited":
+		if op == ":" || op == "~" {
+			break
+		}
+		from, to, err := p.parseTime(value)
+		if err != nil {
+			return nil, err
+		}
+		get := map[string]func(types.Entity) ([]time.Time, bool){
+			"created": createdTimes,
+			"updated": updatedTimes,
+			"visited": visitedTimes,
+		}[field]
+		return timeRange(field, op, value, from, to, get), nil
+	default:
+		return nil, fmt.Errorf("unknown field %s", field)
+	}
+	return nil, fmt.Errorf("operator %s does not apply to %s", op, field)
+}
+
+// parseTime parses the operand of a time comparison into the span it
+// denotes: a whole day in UTC, or an instant.
+func (p *parser) parseTime(value string) (from, to time.Time, err error) {
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, t.Add(time.Nanosecond), nil
+	}
+
+	if len(value) < 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid time %q: expected a day, an RFC 3339 time, or an age such as 90d", value)
+	}
+	unit := value[len(value)-1]
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid time %q: expected a day, an RFC 3339 time, or an age such as 90d", value)
+	}
+	var t time.Time
+	switch unit {
+	case 'd':
+		t = p.now.AddDate(0, 0, -n)
+	case 'w':
+		t = p.now.AddDate(0, 0, -7*n)
+	case 'm':
+		t = p.now.AddDate(0, -n, 0)
+	case 'y':
+		t = p.now.AddDate(-n, 0, 0)
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid age %q: expected a unit of d, w, m, or y", value)
+	}
+	return t, t.Add(time.Nanosecond), nil
+}
@@ -0,0 +1,341 @@
+// Package query implements a filter language for selecting entities.
+//
+// An expression combines predicates with and, or, and not (also written &&,
+// ||, and !), grouped with parentheses; and binds tighter than or. The
+// predicates are:
+//
+//	label:go            the entity has the label go (tag is an alias)
+//	label~^go           some label matches the regular expression
+//	name:gopher         some name contains gopher, ignoring case (title is an alias)
+//	name~"^The Go"      some name matches the regular expression
+//	url:/blog/          the URL contains /blog/
+//	url~"\.pdf$"        the URL matches the regular expression
+//	host:example.com    the host is example.com or one of its subdomains (domain is an alias)
+//	source:export.json  a recorded source of the entity has a file name containing export.json
+//	created>=2024-01-01 the entity was created on or after that day
+//	visited<90d         the entity was last visited more than 90 days ago
+//	updated>=1w         the entity was updated in the last week
+//	toread, shared, feed
+//	                    the flag is true
+//	edges               the entity has edges
+//	link:child-of       the entity has a link of that kind to another
+//
+// Expressions are evaluated in Belnap's four-valued logic. A predicate on an
+// unset attribute, such as toread on an entity with no ToRead value or
+// visited on one never visited, is Unknown rather than false, and the
+// connectives carry that through: not Unknown is Unknown, Unknown and False is
+// False, and Unknown or True is True. Likewise a flag that merged sources
+// disagree on is Both, true and false at once. Filters keep only the
+// entities an expression is True for.
+//
+// Times compare with <, <=, >, and >=, against a day (2006-01-02), an RFC
+// 3339 instant, or an age before now in days (d), weeks (w), months (m), or
+// years (y). A day covers all of its 24 hours in UTC, so created<=2024-03-31
+// includes the 31st. Values holding spaces or parentheses are written in
+// double quotes, with Go escapes.
+package query
+
+import (
+	"fmt"
+	"iter"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/belnap"
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+// Expr is a parsed filter expression.
+type Expr interface {
+	// Eval returns the value of the expression on one node.
+	Eval(n types.Node) belnap.Value
+	// column returns the values of the expression on each of nodes.
+	column(nodes []types.Node) belnap.Vec
+	String() string
+}
+
+// Matches reports whether expr is True for n.
+func Matches(expr Expr, n types.Node) bool {
+	return expr.Eval(n) == belnap.True
+}
+
+type And struct{ X, Y Expr }
+
+func (e And) Eval(n types.Node) belnap.Value { return e.X.Eval(n).And(e.Y.Eval(n)) }
+
+func (e And) column(nodes []types.Node) belnap.Vec {
+	return e.X.column(nodes).And(e.Y.column(nodes))
+}
+
+func (e And) String() string { return fmt.Sprintf("(%s and %s)", e.X, e.Y) }
+
+type Or struct{ X, Y Expr }
+
+func (e Or) Eval(n types.Node) belnap.Value { return e.X.Eval(n).Or(e.Y.Eval(n)) }
+
+func (e Or) column(nodes []types.Node) belnap.Vec {
+	return e.X.column(nodes).Or(e.Y.column(nodes))
+}
+
+func (e Or) String() string { return fmt.Sprintf("(%s or %s)", e.X, e.Y) }
+
+type Not struct{ X Expr }
+
+func (e Not) Eval(n types.Node) belnap.Value { return e.X.Eval(n).Not() }
+
+func (e Not) column(nodes []types.Node) belnap.Vec { return e.X.column(nodes).Not() }
+
+func (e Not) String() string { return fmt.Sprintf("not %s", e.X) }
+
+// Predicate tests one attribute of a node. Field and Op are as written in
+// the expression, with aliases resolved, and Value is the operand, if any.
+type Predicate struct {
+	Field string
+	Op    string
+	Value string
+	test  func(n types.Node) belnap.Value
+}
+
+func (p Predicate) Eval(n types.Node) belnap.Value {
+	return p.test(n)
+}
+
+func (p Predicate) column(nodes []types.Node) belnap.Vec {
+	values := make([]belnap.Value, len(nodes))
+	for i, n := range nodes {
+		values[i] = p.test(n)
+	}
+	return belnap.FromSlice(values)
+}
+
+func (p Predicate) String() string {
+	if p.Op == "" {
+		return p.Field
+	}
+	return p.Field + p.Op + quoteValue(p.Value)
+}
+
+// quoteValue quotes a value that would not parse back as a bare word.
+func quoteValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\r\n()\"") {
+		return fmt.Sprintf("%q", v)
+	}
+	return v
+}
+
+// Filter returns an iterator over the nodes that match expr, passing errors
+// through. Edges to nodes filtered out are left in place; types.Collect
+// drops them.
+func Filter(nodes iter.Seq2[types.Node, error], expr Expr) iter.Seq2[types.Node, error] {
+	return func(yield func(types.Node, error) bool) {
+		for node, err := range nodes {
+			if err == nil && !Matches(expr, node) {
+				continue
+			}
+			if !yield(node, err) {
+				return
+			}
+		}
+	}
+}
+
+// FilterCollection returns a collection of the entities of coll that expr
+// is True for, with the edges between them.
+func FilterCollection(coll *types.Collection, expr Expr) types.Collection {
+	values := Column(coll, expr)
+	kept := func(yield func(types.Node, error) bool) {
+		for node := range coll.Stream() {
+			if v, _ := values.Get(int(node.ID)); v == belnap.True {
+				if !yield(node, nil) {
+					return
+				}
+			}
+		}
+	}
+	// Nodes from a collection carry no errors.
+	filtered, _ := types.Collect(kept)
+	return filtered
+}
+
+// known converts a determined outcome to its truth value.
+func known(b bool) belnap.Value {
+	if b {
+		return belnap.True
+	}
+	return belnap.False
+}
+
+// flagPredicate tests a flag, which is Unknown when unset and Both when its
+// sources disagreed on it.
+func flagPredicate(field string, get func(types.Entity) belnap.Value) Predicate {
+	return Predicate{
+		Field: field,
+		test:  func(n types.Node) belnap.Value { return get(n.Entity) },
+	}
+}
+
+func hasEdges() Predicate {
+	return Predicate{
+		Field: "edges",
+		test:  func(n types.Node) belnap.Value { return known(len(n.Edges) > 0) },
+	}
+}
+
+func hasLink(kind types.LinkKind) Predicate {
+	return Predicate{
+		Field: "link",
+		Op:    ":",
+		Value: string(kind),
+		test: func(n types.Node) belnap.Value {
+			return known(slices.ContainsFunc(n.Links, func(l types.NodeLink) bool { return l.Kind == kind }))
+		},
+	}
+}
+
+func labelIs(label string) Predicate {
+	return Predicate{
+		Field: "label",
+		Op:    ":",
+		Value: label,
+		test: func(n types.Node) belnap.Value {
+			_, ok := n.Entity.Labels[types.Label(label)]
+			return known(ok)
+		},
+	}
+}
+
+func anyMatches[K ~string](field string, re *regexp.Regexp, values func(types.Entity) map[K]struct{}) Predicate {
+	return Predicate{
+		Field: field,
+		Op:    "~",
+		Value: re.String(),
+		test: func(n types.Node) belnap.Value {
+			for v := range values(n.Entity) {
+				if re.MatchString(string(v)) {
+					return belnap.True
+				}
+			}
+			return belnap.False
+		},
+	}
+}
+
+func nameContains(s string) Predicate {
+	lower := strings.ToLower(s)
+	return Predicate{
+		Field: "name",
+		Op:    ":",
+		Value: s,
+		test: func(n types.Node) belnap.Value {
+			for name := range n.Entity.Names {
+				if strings.Contains(strings.ToLower(string(name)), lower) {
+					return belnap.True
+				}
+			}
+			return belnap.False
+		},
+	}
+}
+
+func urlMatches(op, value string, match func(string) bool) Predicate {
+	return Predicate{
+		Field: "url",
+		Op:    op,
+		Value: value,
+		test: func(n types.Node) belnap.Value {
+			if n.Entity.URI == nil {
+				return belnap.Unknown
+			}
+			return known(match(n.Entity.URI.String()))
+		},
+	}
+}
+
+func hostIs(domain string) Predicate {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return Predicate{
+		Field: "host",
+		Op:    ":",
+		Value: domain,
+		test: func(n types.Node) belnap.Value {
+			if n.Entity.URI == nil {
+				return belnap.Unknown
+			}
+			host := strings.ToLower(n.Entity.URI.Hostname())
+			return known(host == domain || strings.HasSuffix(host, "."+domain))
+		},
+	}
+}
+
+// sourceContains tests the recorded provenance of an entity, which is
+// Unknown for an entity whose sources were not recorded.
+func sourceContains(s string) Predicate {
+	return Predicate{
+		Field: "source",
+		Op:    ":",
+		Value: s,
+		test: func(n types.Node) belnap.Value {
+			if len(n.Entity.Provenance) == 0 {
+				return belnap.Unknown
+			}
+			for _, c := range n.Entity.Provenance {
+				if strings.Contains(c.Source.File, s) {
+					return belnap.True
+				}
+			}
+			return belnap.False
+		},
+	}
+}
+
+// timeRange compares a time against the span [from, to): a day, or a single
+// instant when to is just after from.
+func timeRange(field, op, value string, from, to time.Time, get func(types.Entity) ([]time.Time, bool)) Predicate {
+	var in func(time.Time) bool
+	switch op {
+	case "<":
+		in = func(t time.Time) bool { return t.Before(from) }
+	case "<=":
+		in = func(t time.Time) bool { return t.Before(to) }
+	case ">":
+		in = func(t time.Time) bool { return !t.Before(to) }
+	case ">=":
+		in = func(t time.Time) bool { return !t.Before(from) }
+	}
+
+	return Predicate{
+		Field: field,
+		Op:    op,
+		Value: value,
+		test: func(n types.Node) belnap.Value {
+			times, ok := get(n.Entity)
+			if !ok {
+				return belnap.Unknown
+			}
+			return known(slices.ContainsFunc(times, in))
+		},
+	}
+}
+
+func createdTimes(e types.Entity) ([]time.Time, bool) {
+	return []time.Time{time.Time(e.CreatedAt)}, true
+}
+
+// updatedTimes lists every update, so a range matches an entity updated at
+// any time within it. An entity never updated is known not to match.
+func updatedTimes(e types.Entity) ([]time.Time, bool) {
+	times := make([]time.Time, len(e.UpdatedAt))
+	for i, u := range e.UpdatedAt {
+		times[i] = time.Time(u)
+	}
+	return times, true
+}
+
+func visitedTimes(e types.Entity) ([]time.Time, bool) {
+	if t, ok := e.LastVisitedAt.Get(); ok {
+		return []time.Time{t}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,184 @@
+package query
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrytill/hbt-go/internal/types"
+)
+
+var now = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func makeNode(uri string, created time.Time, labels ...string) types.Node {
+	u, err := url.Parse(uri)
+	if err != nil {
+		panic(err)
+	}
+	entity := types.Entity{
+		URI:       u,
+		CreatedAt: types.CreatedAt(created),
+		UpdatedAt: []types.UpdatedAt{},
+		Names:     map[types.Name]struct{}{"The Go Blog": {}},
+		Labels:    make(map[types.Label]struct{}),
+	}
+	for _, label := range labels {
+		entity.Labels[types.Label(label)] = struct{}{}
+	}
+	return types.Node{Entity: entity}
+}
+
+func TestMatch(t *testing.T) {
+	node := makeNode("https://go.dev/blog/intro", time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC), "go", "blog")
+	node.Entity.Shared = types.NewShared(true)
+	node.Entity.ToRead = types.NewToRead(true)
+	node.Edges = []uint{1}
+	node.Links = []types.NodeLink{{To: 1, Kind: types.LinkCites}}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"label:go", true},
+		{"tag:rust", false},
+		{"label~^bl", true},
+		{"name:\"go blog\"", true},
+		{`name~"^The Go"`, true},
+		{"url:/blog/", true},
+		{`url~"\\.pdf$"`, false},
+		{"host:go.dev", true},
+		{"domain:dev", true},
+		{"host:o.dev", false},
+		{"created>=2024-04-01 and created<2024-07-01", true},
+		{"created<=2024-05-10", true},
+		{"created>2024-05-10", false},
+		{"created>=3m", true},
+		{"created>=30d", false},
+		{"updated>=1y", false},
+		{"shared && toread && label:go", true},
+		{"shared and not toread", false},
+		{"feed", false},
+		{"not feed", false}, // unset, so Unknown either way
+		{"visited<1d", false},
+		{"edges", true},
+		{"link:cites", true},
+		{"link:child-of", false},
+		{"label:rust or label:go and shared", true},
+		{"(label:rust or label:go) and not shared", false},
+		{"!(label:rust)", true},
+		{"source:export", false}, // no provenance, so Unknown either way
+		{"not source:export", false},
+	}
+
+	for _, tt := range tests {
+		expr, err := parse(tt.query, now)
+		if err != nil {
+			t.Errorf("parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := Matches(expr, node); got != tt.want {
+			t.Errorf("%q (%s) = %v, want %v", tt.query, expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseString(t *testing.T) {
+	tests := map[string]string{
+		"label:go and shared or toread": "((label:go and shared) or toread)",
+		"not (tag:a || title:\"b c\")":  `not (label:a or name:"b c")`,
+		"  edges  ":                     "edges",
+	}
+	for query, want := range tests {
+		expr, err := parse(query, now)
+		if err != nil {
+			t.Errorf("parse(%q): %v", query, err)
+			continue
+		}
+		if got := expr.String(); got != want {
+			t.Errorf("parse(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                       "end of query",
+		"label:go and":           "end of query",
+		"(label:go":              "missing )",
+		"label":                  "expected an operator",
+		"colour:red":             "unknown field colour",
+		"host~go":                "does not apply",
+		"created>=yesterday":     "invalid time",
+		"created>=3h":            "invalid age",
+		`created>""`:             "invalid time",
+		"created>d":              "invalid time",
+		"name~(":                 "missing value",
+		`name~"("`:               "missing closing )",
+		"label:go organic":       "unexpected",
+		`name:"unterminated`:     "unterminated string",
+		"label:go and or toread": "expected a predicate",
+		"link:parent":            "invalid link kind",
+		"link~cites":             "does not apply",
+	}
+	for query, want := range tests {
+		_, err := parse(query, now)
+		if err == nil {
+			t.Errorf("parse(%q): expected an error", query)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("parse(%q) = %v, want an error containing %q", query, err, want)
+		}
+	}
+}
+
+func TestFilterCollection(t *testing.T) {
+	coll := types.NewCollection()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := coll.Upsert(makeNode("https://example.com/a", day, "go").Entity)
+	b := coll.Upsert(makeNode("https://example.com/b", day).Entity)
+	c := coll.Upsert(makeNode("https://example.com/c", day, "go").Entity)
+	coll.AddEdges(a, b)
+	coll.AddEdges(a, c)
+
+	expr, err := parse("label:go", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := FilterCollection(&coll, expr)
+	if filtered.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", filtered.Len())
+	}
+
+	edges := 0
+	for id := range filtered.Nodes() {
+		for range filtered.Neighbors(id) {
+			edges++
+		}
+	}
+	if edges != 2 {
+		t.Errorf("edges = %d, want 2 (a to c, both ways)", edges)
+	}
+}
+
+func TestSource(t *testing.T) {
+	node := makeNode("https://go.dev/blog/intro", now, "go")
+	node.Entity.RecordSource(types.Source{File: "exports/pinboard.json", Format: "pinboard_json", Record: 3})
+
+	tests := map[string]bool{
+		"source:pinboard":      true,
+		"source:exports/":      true,
+		"source:delicious":     false,
+		"not source:delicious": true,
+	}
+	for query, want := range tests {
+		expr, err := parse(query, now)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", query, err)
+		}
+		if got := Matches(expr, node); got != want {
+			t.Errorf("%q = %v, want %v", query, got, want)
+		}
+	}
+}
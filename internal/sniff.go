@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/henrytill/hbt-go/internal/delimited"
+)
+
+// ErrUnknownContent is returned by SniffInputFormat when the input matches
+// none of the formats it recognizes.
+var ErrUnknownContent = errors.New("content matches no known input format")
+
+// sqliteMagic starts every SQLite database file.
+const sqliteMagic = "SQLite format 3\x00"
+
+var utf8BOM = []byte("\ufeff")
+
+var (
+	sniffMarkdownLink     = regexp.MustCompile(`\[[^\[\]]*\]\(\S+\)`)
+	sniffMarkdownAutoItem = regexp.MustCompile(`^\s*[-*+]\s+<[A-Za-z][A-Za-z0-9+.-]*:[^\s<>]+>`)
+	sniffMarkdownHeading  = regexp.MustCompile(`^#{1,6}\s`)
+	sniffOrgLink          = regexp.MustCompile(`\[\[[^\[\]]+\]`)
+	sniffOrgKeyword       = regexp.MustCompile(`^(?:#\+\w+:|\s*:PROPERTIES:\s*$)`)
+)
+
+// DetectInput identifies the format of the input named filename. A file
+// extension settles the format, except that .json and .xml are each shared
+// by more than one format, which are told apart by content. Without a
+// recognized extension, as for stdin, the format is identified by content
+// alone with SniffInputFormat.
+func DetectInput(filename string, r *bufio.Reader) (Format, error) {
+	format, ok := DetectInputFormat(filename)
+	if !ok {
+		return SniffInputFormat(r)
+	}
+
+	switch format {
+	case JSON:
+		if sniffed, ok := SniffJSONFormat(r); ok {
+			return sniffed, nil
+		}
+	case XML:
+		if sniffed, err := SniffInputFormat(r); err == nil && sniffed == OPML {
+			return sniffed, nil
+		}
+	}
+
+	return format, nil
+}
+
+// SniffInputFormat identifies the format of r by peeking at its start
+// without consuming it. It recognizes a Firefox places database, the JSON
+// formats told apart by SniffJSONFormat, Netscape bookmark files, Pinboard
+// XML exports, OPML, hbt YAML documents, CSV and TSV files with a uri
+// column, and Markdown and Org files by their links, headings, and
+// keywords, a Markdown link being either [text](url) or a list item holding
+// an autolink such as <https://go.dev/>. Input that matches none of them
+// returns ErrUnknownContent, and text that could be either Markdown or Org
+// returns an error naming both.
+func SniffInputFormat(r *bufio.Reader) (Format, error) {
+	head, _ := r.Peek(sniffLimit)
+
+	if bytes.HasPrefix(head, []byte(sqliteMagic)) {
+		return Firefox, nil
+	}
+
+	text := bytes.TrimPrefix(head, utf8BOM)
+	trimmed := bytes.TrimLeft(text, " \t\r\n")
+	if len(trimmed) == 0 {
+		return Format{}, errors.New("input is empty")
+	}
+
+	switch trimmed[0] {
+	case '{':
+		if format, ok := SniffJSONFormat(r); ok {
+			return format, nil
+		}
+		return Format{}, ErrUnknownContent
+	case '[':
+		// Markdown and Org text may start with a link.
+		if format, ok := SniffJSONFormat(r); ok {
+			return format, nil
+		}
+	case '<':
+		return sniffMarkup(trimmed)
+	}
+
+	// A peek that filled the buffer may end mid-line.
+	if len(head) == sniffLimit {
+		if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+			text = text[:i]
+		}
+	}
+
+	return sniffText(strings.Split(string(text), "\n"))
+}
+
+// sniffMarkup identifies an HTML or XML document by its doctype or root
+// element.
+func sniffMarkup(head []byte) (Format, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(head))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return Format{}, ErrUnknownContent
+		}
+
+		switch t := token.(type) {
+		case xml.Directive:
+			fields := strings.Fields(string(t))
+			if len(fields) >= 2 && strings.EqualFold(fields[0], "DOCTYPE") {
+				switch strings.ToLower(fields[1]) {
+				case "netscape-bookmark-file-1", "html":
+					return HTML, nil
+				}
+			}
+		case xml.StartElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "posts":
+				return XML, nil
+			case "opml":
+				return OPML, nil
+			// Netscape bookmark files written without a doctype start
+			// with one of these.
+			case "html", "meta", "title", "h1", "dl":
+				return HTML, nil
+			default:
+				return Format{}, fmt.Errorf("%w: unrecognized root element <%s>", ErrUnknownContent, t.Name.Local)
+			}
+		}
+	}
+}
+
+// sniffText identifies a text format from the first lines of the input.
+func sniffText(lines []string) (Format, error) {
+	// An hbt YAML document declares its version first, perhaps after a
+	// document marker or comments.
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "version:") {
+			return YAML, nil
+		}
+		break
+	}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if format, ok := sniffDelimitedHeader(line); ok {
+			return format, nil
+		}
+		break
+	}
+
+	var markdown, org bool
+	for _, line := range lines {
+		if sniffMarkdownLink.MatchString(line) || sniffMarkdownHeading.MatchString(line) ||
+			sniffMarkdownAutoItem.MatchString(line) {
+			markdown = true
+		}
+		if sniffOrgLink.MatchString(line) || sniffOrgKeyword.MatchString(line) {
+			org = true
+		}
+	}
+
+	switch {
+	case markdown && org:
+		return Format{}, fmt.Errorf("content is ambiguous between %s and %s", Markdown, Org)
+	case markdown:
+		return Markdown, nil
+	case org:
+		return Org, nil
+	default:
+		return Format{}, ErrUnknownContent
+	}
+}
+
+// sniffDelimitedHeader recognizes the header of a CSV or TSV file by a
+// column holding the uri field.
+func sniffDelimitedHeader(line string) (Format, bool) {
+	for _, candidate := range []struct {
+		format Format
+		comma  string
+	}{{TSV, "\t"}, {CSV, ","}} {
+		if !strings.Contains(line, candidate.comma) {
+			continue
+		}
+		for _, column := range strings.Split(line, candidate.comma) {
+			if delimited.FieldOf(strings.Trim(column, `" `)) == delimited.FieldURI {
+				return candidate.format, true
+			}
+		}
+	}
+	return Format{}, false
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSniffInputFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Format
+	}{
+		{"netscape", "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n<META HTTP-EQUIV=\"Content-Type\">\n<DL><p>", HTML},
+		{"netscape without doctype", "<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html\">\n<TITLE>Bookmarks</TITLE>", HTML},
+		{"pinboard json", `[{"href": "https://example.com/"}]`, JSON},
+		{"pinboard xml", "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<posts user=\"u\">\n</posts>", XML},
+		{"opml", "<?xml version=\"1.0\"?>\n<!-- feeds -->\n<opml version=\"2.0\">", OPML},
+		{"hbt json", "{\n  \"version\": \"0.1.0\",\n  \"length\": 0,\n  \"value\": []\n}", HBTJSON},
+		{"hbt yaml", "version: 0.1.0\nlength: 0\nvalue: []\n", YAML},
+		{"hbt yaml with marker", "---\n# exported\nversion: v0.1.0\n", YAML},
+		{"jsonl", "{\"version\":\"0.1.0\"}\n", JSONL},
+		{"chrome", `{"checksum": "abc", "roots": {}}`, Chrome},
+		{"markdown", "# November 15, 2023\n\n- [Go](https://go.dev/)\n", Markdown},
+		{"org", "#+TITLE: Links\n* [2023-11-15 Wed]\n- [[https://go.dev/][Go]]\n", Org},
+		{"markdown starting with a link", "[Go](https://go.dev/)\n", Markdown},
+		{"markdown autolinks", "- <https://go.dev/>\n- <https://example.com/>\n", Markdown},
+		{"org starting with a link", "[[https://go.dev/][Go]]\n", Org},
+		{"empty pinboard json", "[]", JSON},
+		{"csv", "\ufeffURI,names\nhttps://go.dev/,Go\n", CSV},
+		{"tsv", "names\turi\tlabels\n", TSV},
+		{"firefox", "SQLite format 3\x00\x10\x00", Firefox},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.content))
+			got, err := SniffInputFormat(r)
+			if err != nil {
+				t.Fatalf("SniffInputFormat: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SniffInputFormat = %v, want %v", got, tt.want)
+			}
+			if rest, _ := r.Peek(len(tt.content)); string(rest) != tt.content {
+				t.Errorf("SniffInputFormat consumed input, %q left", rest)
+			}
+		})
+	}
+}
+
+func TestSniffInputFormatErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		unknown bool
+	}{
+		{"empty", " \n\t", false},
+		{"plain text", "just some notes\n", true},
+		{"atom feed", "<?xml version=\"1.0\"?>\n<feed xmlns=\"http://www.w3.org/2005/Atom\">", true},
+		{"json scalar", `"string"`, true},
+		{"json array of numbers", `[1, 2, 3]`, true},
+		{"markdown or org", "# Links\n- [[https://go.dev/][Go]] and [Go](https://go.dev/)\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SniffInputFormat(bufio.NewReader(strings.NewReader(tt.content)))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if errors.Is(err, ErrUnknownContent) != tt.unknown {
+				t.Errorf("error %q: errors.Is(ErrUnknownContent) = %v, want %v", err, !tt.unknown, tt.unknown)
+			}
+		})
+	}
+}
+
+func TestDetectInput(t *testing.T) {
+	tests := []struct {
+		filename string
+		content  string
+		want     Format
+	}{
+		{"bookmarks.md", `{"version": "0.1.0"}`, Markdown},
+		{"feeds.xml", "<opml version=\"2.0\">", OPML},
+		{"posts.xml", "<posts>", XML},
+		{"broken.xml", "not xml", XML},
+		{"export.json", `{"roots": {}}`, Chrome},
+		{"-", "version: 0.1.0\n", YAML},
+		{"bookmarks", "<!DOCTYPE NETSCAPE-Bookmark-file-1>", HTML},
+	}
+
+	for _, tt := range tests {
+		got, err := DetectInput(tt.filename, bufio.NewReader(strings.NewReader(tt.content)))
+		if err != nil {
+			t.Errorf("DetectInput(%q): %v", tt.filename, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("DetectInput(%q) = %v, want %v", tt.filename, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
 	"net/url"
@@ -14,19 +15,35 @@ import (
 type Collection struct {
 	entities []Entity
 	edges    [][]uint
-	urls     map[string]uint
+	// links holds the links from each entity.
+	links [][]link
+	urls  map[string]uint
+	// normalize keys urls; see SetNormalizer.
+	normalize Normalizer
+	// keys holds the key of the Id of each entity, and indices maps each
+	// key back to the entity's index, which Remove changes.
+	keys    []uint
+	indices map[uint]uint
+	nextKey uint
+	// absorbed counts the entities Upsert has absorbed.
+	absorbed int
 }
 
+// Id identifies an entity of a collection. It stays valid, and goes on
+// naming the same entity, when other entities are removed.
 type Id struct {
 	owner *Collection
-	index uint
+	key   uint
 }
 
 func NewCollection() Collection {
 	return Collection{
 		entities: []Entity{},
 		edges:    [][]uint{},
+		links:    [][]link{},
 		urls:     make(map[string]uint),
+		keys:     []uint{},
+		indices:  make(map[uint]uint),
 	}
 }
 
@@ -36,6 +53,28 @@ func (c *Collection) checkId(id Id) {
 	}
 }
 
+// indexOf returns the current index of the entity named by id.
+func (c *Collection) indexOf(id Id) uint {
+	c.checkId(id)
+	index, ok := c.indices[id.key]
+	if !ok {
+		panic("collection: removed id")
+	}
+	return index
+}
+
+// idAt returns the Id of the entity at index.
+func (c *Collection) idAt(index uint) Id {
+	return Id{owner: c, key: c.keys[index]}
+}
+
+// appendKey assigns a key to an entity appended at index.
+func (c *Collection) appendKey(index uint) {
+	c.keys = append(c.keys, c.nextKey)
+	c.indices[c.nextKey] = index
+	c.nextKey++
+}
+
 func NewCollectionFromPosts(posts []pinboard.Post) (Collection, error) {
 	coll := NewCollection()
 
@@ -50,57 +89,236 @@ func NewCollectionFromPosts(posts []pinboard.Post) (Collection, error) {
 	return coll, nil
 }
 
-func (c *Collection) findEntity(uri *url.URL) (Id, bool) {
+func (c *Collection) findIndex(uri *url.URL) (uint, bool) {
 	if uri == nil {
-		return Id{}, false
+		return 0, false
 	}
-	index, exists := c.urls[uri.String()]
+	index, exists := c.urls[c.key(uri)]
+	return index, exists
+}
+
+// Lookup returns the Id of the entity with the URL uri, or of the entity
+// whose URL normalizes to the same key if c has a normalizer.
+func (c *Collection) Lookup(uri *url.URL) (Id, bool) {
+	index, exists := c.findIndex(uri)
 	if !exists {
 		return Id{}, false
 	}
-	return Id{owner: c, index: index}, true
+	return c.idAt(index), true
 }
 
 func (c *Collection) insert(entity Entity) Id {
 	index := uint(len(c.entities))
 	c.entities = append(c.entities, entity)
 	c.edges = append(c.edges, []uint{})
-	c.urls[entity.URI.String()] = index
-	return Id{owner: c, index: index}
+	c.links = append(c.links, nil)
+	c.urls[c.key(entity.URI)] = index
+	c.appendKey(index)
+	return c.idAt(index)
 }
 
 func (c *Collection) Upsert(entity Entity) Id {
-	if id, exists := c.findEntity(entity.URI); exists {
-		c.entities[id.index].absorb(entity)
-		return id
+	if index, exists := c.findIndex(entity.URI); exists {
+		c.entities[index].absorb(entity)
+		c.absorbed++
+		return c.idAt(index)
 	}
 
 	return c.insert(entity)
 }
 
+// Absorbed reports how many entities Upsert has absorbed into one c already
+// had, rather than added. For a collection a parser built, it is the number
+// of records that repeated an earlier one.
+func (c *Collection) Absorbed() int {
+	return c.absorbed
+}
+
+// Get returns the entity named by id. It is a copy, as with Entities; use
+// Update to change it.
+func (c *Collection) Get(id Id) Entity {
+	return c.entities[c.indexOf(id)]
+}
+
+// Update calls f with the entity named by id, which f may change in place.
+// If f changes the URL to one another entity of c has, Update keeps the old
+// URL and returns an error; other changes f made are kept.
+func (c *Collection) Update(id Id, f func(*Entity)) error {
+	index := c.indexOf(id)
+	entity := &c.entities[index]
+	old := entity.URI
+	f(entity)
+
+	if entity.URI == nil {
+		entity.URI = old
+		return errors.New("collection: entity URL removed")
+	}
+	oldKey, newKey := c.key(old), c.key(entity.URI)
+	if oldKey == newKey {
+		return nil
+	}
+	if other, exists := c.urls[newKey]; exists && other != index {
+		uri := entity.URI
+		entity.URI = old
+		return fmt.Errorf("collection: %s is already in the collection", uri)
+	}
+	if c.urls[oldKey] == index {
+		c.refile(oldKey)
+	}
+	c.urls[newKey] = index
+	return nil
+}
+
+// Remove removes the entity named by id, with its edges and the links from
+// and to it, and returns it. The entities after it move down to close the
+// gap, keeping their Ids; id itself is no longer valid. To remove entities
+// found by iterating over c, collect their Ids first, as removal shifts the
+// entities being iterated over. An entity that shared the removed entity's
+// key under a normalizer, as SetNormalizer may leave one, is found by URL in
+// its place.
+func (c *Collection) Remove(id Id) Entity {
+	index := c.indexOf(id)
+	removed := c.entities[index]
+
+	c.entities = slices.Delete(c.entities, int(index), int(index)+1)
+	c.edges = slices.Delete(c.edges, int(index), int(index)+1)
+	c.links = slices.Delete(c.links, int(index), int(index)+1)
+	c.keys = slices.Delete(c.keys, int(index), int(index)+1)
+	delete(c.indices, id.key)
+
+	shift := func(i uint) uint {
+		if i > index {
+			return i - 1
+		}
+		return i
+	}
+
+	// Edge lists may be shared with nodes yielded by Stream, so they are
+	// rewritten into new slices rather than in place.
+	for i, edges := range c.edges {
+		if !slices.ContainsFunc(edges, func(to uint) bool { return to >= index }) {
+			continue
+		}
+		rewritten := make([]uint, 0, len(edges))
+		for _, to := range edges {
+			if to != index {
+				rewritten = append(rewritten, shift(to))
+			}
+		}
+		c.edges[i] = rewritten
+	}
+	for i, links := range c.links {
+		if !slices.ContainsFunc(links, func(l link) bool { return l.to >= index }) {
+			continue
+		}
+		rewritten := make([]link, 0, len(links))
+		for _, l := range links {
+			if l.to != index {
+				rewritten = append(rewritten, link{to: shift(l.to), kind: l.kind})
+			}
+		}
+		c.links[i] = rewritten
+	}
+
+	var orphaned []string
+	for key, i := range c.urls {
+		if i == index {
+			orphaned = append(orphaned, key)
+		} else {
+			c.urls[key] = shift(i)
+		}
+	}
+	for _, key := range orphaned {
+		c.refile(key)
+	}
+	for i, key := range c.keys[index:] {
+		c.indices[key] = index + uint(i)
+	}
+
+	return removed
+}
+
+// refile points key at the first entity of c filed under it, or drops it if
+// there is none, once the entity it pointed at is gone or has a new URL.
+// Entities that SetNormalizer left apart may share a key.
+func (c *Collection) refile(key string) {
+	delete(c.urls, key)
+	for i, entity := range c.entities {
+		if c.key(entity.URI) == key {
+			c.urls[key] = uint(i)
+			return
+		}
+	}
+}
+
 func (c *Collection) AddEdges(from, to Id) {
-	c.checkId(from)
-	c.checkId(to)
+	f, t := c.indexOf(from), c.indexOf(to)
 
-	c.edges[from.index] = append(c.edges[from.index], to.index)
-	c.edges[to.index] = append(c.edges[to.index], from.index)
+	c.edges[f] = append(c.edges[f], t)
+	c.edges[t] = append(c.edges[t], f)
 }
 
-func (c *Collection) ApplyMappings(mappings map[string]string) {
-	for i := range c.entities {
-		entity := &c.entities[i]
+// RemoveEdges removes every edge between from and to, in both directions,
+// and reports whether there were any.
+func (c *Collection) RemoveEdges(from, to Id) bool {
+	f, t := c.indexOf(from), c.indexOf(to)
+
+	removed := false
+	for _, pair := range [][2]uint{{f, t}, {t, f}} {
+		edges := c.edges[pair[0]]
+		if !slices.Contains(edges, pair[1]) {
+			continue
+		}
+		c.edges[pair[0]] = slices.DeleteFunc(slices.Clone(edges), func(i uint) bool { return i == pair[1] })
+		removed = true
+	}
+	return removed
+}
 
-		newLabels := make(map[Label]struct{})
+// Merge upserts the entities of other into c in insertion order, carrying
+// their edges and links over, and reports how many entities were new to c
+// and how many were absorbed into an entity c already had. Edges and links c
+// already has, and those that would join an entity to itself because both
+// ends were absorbed into it, are not added. Entities new to c share their
+// interior maps and slices with other.
+func (c *Collection) Merge(other *Collection) (added, absorbed int) {
+	indices := make([]uint, len(other.entities))
+	for i, entity := range other.entities {
+		before := len(c.entities)
+		id := c.Upsert(entity)
+		indices[i] = c.indexOf(id)
+		if len(c.entities) > before {
+			added++
+		} else {
+			absorbed++
+		}
+	}
 
-		for label := range entity.Labels {
-			if newLabel, exists := mappings[string(label)]; exists {
-				newLabels[Label(newLabel)] = struct{}{}
-			} else {
-				newLabels[label] = struct{}{}
+	for i, edges := range other.edges {
+		from := indices[i]
+		for _, to := range edges {
+			if indices[to] == from || slices.Contains(c.edges[from], indices[to]) {
+				continue
 			}
+			c.edges[from] = append(c.edges[from], indices[to])
 		}
+	}
 
-		entity.Labels = newLabels
+	for i, links := range other.links {
+		from := indices[i]
+		for _, l := range links {
+			if indices[l.to] != from {
+				c.addLink(from, link{to: indices[l.to], kind: l.kind})
+			}
+		}
+	}
+
+	return added, absorbed
+}
+
+func (c *Collection) ApplyMappings(mappings map[string]string) {
+	for i := range c.entities {
+		c.entities[i].ApplyMappings(mappings)
 	}
 }
 
@@ -116,9 +334,54 @@ func (c *Collection) Entities() iter.Seq[Entity] {
 	return slices.Values(c.entities)
 }
 
+// Nodes returns an iterator over the collection's ids and entities in
+// insertion order. The entities are copies, as with Entities.
+func (c *Collection) Nodes() iter.Seq2[Id, Entity] {
+	return func(yield func(Id, Entity) bool) {
+		for i, entity := range c.entities {
+			if !yield(c.idAt(uint(i)), entity) {
+				return
+			}
+		}
+	}
+}
+
+// Neighbors returns an iterator over the ids joined to id by AddEdges, in the
+// order the edges were added. An id joined more than once is yielded once per
+// edge.
+func (c *Collection) Neighbors(id Id) iter.Seq[Id] {
+	from := c.indexOf(id)
+	return func(yield func(Id) bool) {
+		for _, index := range c.edges[from] {
+			if !yield(c.idAt(index)) {
+				return
+			}
+		}
+	}
+}
+
 type Version string
 
-const ExpectedVersion Version = "v0.1.0"
+// ExpectedVersion is the newest version of the serialized collection, which
+// adds the folder paths of browser exports to version 0.4. Every version
+// from minimumVersion on is read.
+const ExpectedVersion Version = "v0.5.0"
+
+// contradictionsVersion is the version that added contradicted flags to
+// version 0.3.
+const contradictionsVersion Version = "v0.4.0"
+
+// linksVersion is the version that added directed links between entities to
+// version 0.2.
+const linksVersion Version = "v0.3.0"
+
+// provenanceVersion is the version that added entity provenance to version
+// 0.1.
+const provenanceVersion Version = "v0.2.0"
+
+// minimumVersion is the oldest version read. A collection that uses nothing
+// newer is written with it, so tools that only know version 0.1 can read it.
+const minimumVersion Version = "v0.1.0"
 
 func NewVersion(v string) (Version, error) {
 	if len(v) > 0 && v[0] != 'v' {
@@ -139,13 +402,17 @@ func (v Version) String() string {
 }
 
 func (v Version) IsCompatible() bool {
-	return semver.MajorMinor(string(v)) == semver.MajorMinor(string(ExpectedVersion))
+	minor := semver.MajorMinor(string(v))
+	return semver.Compare(minor, semver.MajorMinor(string(minimumVersion))) >= 0 &&
+		semver.Compare(minor, semver.MajorMinor(string(ExpectedVersion))) <= 0
 }
 
 type nodeRepr struct {
 	ID     uint       `yaml:"id"     json:"id"`
 	Entity entityRepr `yaml:"entity" json:"entity"`
 	Edges  []uint     `yaml:"edges"  json:"edges"`
+	// Links require version 0.3.
+	Links []linkRepr `yaml:"links,omitempty" json:"links,omitempty"`
 }
 
 type collectionRepr struct {
@@ -154,6 +421,22 @@ type collectionRepr struct {
 	Value   []nodeRepr `yaml:"value"   json:"value"`
 }
 
+// Version returns the version to declare for c when serialized: the oldest
+// version able to hold it.
+func (c *Collection) Version() Version {
+	switch {
+	case slices.ContainsFunc(c.entities, func(e Entity) bool { return len(e.Folders) > 0 }):
+		return ExpectedVersion
+	case slices.ContainsFunc(c.entities, Entity.isContradicted):
+		return contradictionsVersion
+	case c.hasLinks():
+		return linksVersion
+	case c.HasProvenance():
+		return provenanceVersion
+	}
+	return minimumVersion
+}
+
 func (c *Collection) toRepr() collectionRepr {
 	length := uint(len(c.entities))
 	value := make([]nodeRepr, length)
@@ -163,18 +446,21 @@ func (c *Collection) toRepr() collectionRepr {
 			ID:     i,
 			Entity: c.entities[i].toRepr(),
 			Edges:  c.edges[i],
+			Links:  linksToRepr(c.links[i]),
 		}
 	}
 
 	return collectionRepr{
-		Version: ExpectedVersion.String(),
+		Version: c.Version().String(),
 		Length:  length,
 		Value:   value,
 	}
 }
 
-func (c *Collection) fromRepr(s collectionRepr) error {
-	version, err := NewVersion(s.Version)
+// CheckVersion parses the version declared by serialized data and reports an
+// error unless it is compatible with ExpectedVersion.
+func CheckVersion(v string) error {
+	version, err := NewVersion(v)
 	if err != nil {
 		return fmt.Errorf("invalid version in serialized data: %w", err)
 	}
@@ -187,6 +473,14 @@ func (c *Collection) fromRepr(s collectionRepr) error {
 		)
 	}
 
+	return nil
+}
+
+func (c *Collection) fromRepr(s collectionRepr) error {
+	if err := CheckVersion(s.Version); err != nil {
+		return err
+	}
+
 	length := len(s.Value)
 	if s.Length != uint(length) {
 		return fmt.Errorf(
@@ -198,7 +492,11 @@ func (c *Collection) fromRepr(s collectionRepr) error {
 
 	c.entities = make([]Entity, length)
 	c.edges = make([][]uint, length)
+	c.links = make([][]link, length)
 	c.urls = make(map[string]uint)
+	c.keys = make([]uint, 0, length)
+	c.indices = make(map[uint]uint, length)
+	c.nextKey = 0
 
 	for i, serNode := range s.Value {
 		var entity Entity
@@ -210,13 +508,19 @@ func (c *Collection) fromRepr(s collectionRepr) error {
 				return fmt.Errorf("node %d: edge %d out of range [0, %d)", i, edge, length)
 			}
 		}
+		links, err := linksFromRepr(serNode.Links, length)
+		if err != nil {
+			return fmt.Errorf("node %d: %w", i, err)
+		}
 		c.entities[i] = entity
+		c.links[i] = links
 		if serNode.Edges != nil {
 			c.edges[i] = serNode.Edges
 		} else {
 			c.edges[i] = []uint{}
 		}
-		c.urls[entity.URI.String()] = uint(i)
+		c.urls[c.key(entity.URI)] = uint(i)
+		c.appendKey(uint(i))
 	}
 
 	return nil
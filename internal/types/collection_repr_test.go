@@ -140,3 +140,44 @@ func TestCollectionUnmarshalMalformed(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectionStreamRoundTrip(t *testing.T) {
+	coll := makeReprTestCollection(t)
+
+	var lines [][]byte
+	for node, err := range coll.Stream() {
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		line, err := json.Marshal(node)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		lines = append(lines, line)
+	}
+
+	got, err := Collect(func(yield func(Node, error) bool) {
+		for _, line := range lines {
+			var node Node
+			err := json.Unmarshal(line, &node)
+			if !yield(node, err) || err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	first, err := json.Marshal(&coll)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	second, err := json.Marshal(&got)
+	if err != nil {
+		t.Fatalf("Marshal after round trip: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("stream round trip differs:\nfirst:  %s\nsecond: %s", first, second)
+	}
+}
@@ -39,3 +39,182 @@ func TestAddEdges_wrongCollection_panics(t *testing.T) {
 
 	collA.AddEdges(idA, idB)
 }
+
+func TestNodesAndNeighbors(t *testing.T) {
+	coll := NewCollection()
+
+	a := coll.Upsert(makeEntity("https://example.com/a"))
+	b := coll.Upsert(makeEntity("https://example.com/b"))
+	c := coll.Upsert(makeEntity("https://example.com/c"))
+	coll.AddEdges(b, a)
+	coll.AddEdges(c, a)
+
+	var ids []Id
+	for id, entity := range coll.Nodes() {
+		ids = append(ids, id)
+		if entity.URI.String() != coll.entities[coll.indexOf(id)].URI.String() {
+			t.Errorf("Nodes yielded %s for index %d", entity.URI, coll.indexOf(id))
+		}
+	}
+	if len(ids) != 3 || ids[0] != a || ids[1] != b || ids[2] != c {
+		t.Fatalf("Nodes: got %v, want insertion order", ids)
+	}
+
+	var neighbors []Id
+	for id := range coll.Neighbors(a) {
+		neighbors = append(neighbors, id)
+	}
+	if len(neighbors) != 2 || neighbors[0] != b || neighbors[1] != c {
+		t.Errorf("Neighbors(a): got %v, want [b c]", neighbors)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	coll := NewCollection()
+	a := coll.Upsert(makeEntity("https://example.com/a"))
+	b := coll.Upsert(makeEntity("https://example.com/b"))
+	coll.AddEdges(a, b)
+
+	other := NewCollection()
+	b2 := other.Upsert(makeEntity("https://example.com/b"))
+	c := other.Upsert(makeEntity("https://example.com/c"))
+	other.AddEdges(c, b2)
+
+	added, absorbed := coll.Merge(&other)
+	if added != 1 || absorbed != 1 {
+		t.Errorf("Merge = (%d, %d), want (1, 1)", added, absorbed)
+	}
+	if coll.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", coll.Len())
+	}
+
+	var neighbors []string
+	for id := range coll.Neighbors(b) {
+		neighbors = append(neighbors, coll.Get(id).URI.Path)
+	}
+	if len(neighbors) != 2 || neighbors[0] != "/a" || neighbors[1] != "/c" {
+		t.Errorf("Neighbors(b) = %v, want [/a /c]", neighbors)
+	}
+}
+
+func TestGetLookupUpdate(t *testing.T) {
+	coll := NewCollection()
+	a := coll.Upsert(makeEntity("https://example.com/a"))
+	b := coll.Upsert(makeEntity("https://example.com/b"))
+
+	if id, ok := coll.Lookup(mustParseURL("https://example.com/b")); !ok || id != b {
+		t.Errorf("Lookup(b) = %v, %v, want b", id, ok)
+	}
+	if _, ok := coll.Lookup(mustParseURL("https://example.com/c")); ok {
+		t.Error("Lookup found a URL not in the collection")
+	}
+
+	err := coll.Update(a, func(e *Entity) {
+		e.Labels["go"] = struct{}{}
+		e.URI = mustParseURL("https://example.com/c")
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := coll.Get(a); got.URI.Path != "/c" || len(got.Labels) != 1 {
+		t.Errorf("Get(a) after Update = %s %v", got.URI, got.Labels)
+	}
+	if id, ok := coll.Lookup(mustParseURL("https://example.com/c")); !ok || id != a {
+		t.Errorf("Lookup(c) after Update = %v, %v, want a", id, ok)
+	}
+	if _, ok := coll.Lookup(mustParseURL("https://example.com/a")); ok {
+		t.Error("Lookup found the old URL after Update")
+	}
+
+	err = coll.Update(a, func(e *Entity) { e.URI = mustParseURL("https://example.com/b") })
+	if err == nil {
+		t.Error("Update to a URL already in the collection: expected an error")
+	}
+	if got := coll.Get(a).URI.Path; got != "/c" {
+		t.Errorf("failed Update changed the URL to %s", got)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	coll := NewCollection()
+	a := coll.Upsert(makeEntity("https://example.com/a"))
+	b := coll.Upsert(makeEntity("https://example.com/b"))
+	c := coll.Upsert(makeEntity("https://example.com/c"))
+	d := coll.Upsert(makeEntity("https://example.com/d"))
+	coll.AddEdges(a, b)
+	coll.AddEdges(a, c)
+	coll.AddEdges(c, d)
+
+	if got := coll.Remove(b); got.URI.Path != "/b" {
+		t.Errorf("Remove(b) returned %s", got.URI)
+	}
+	if coll.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", coll.Len())
+	}
+
+	// Ids of the entities that moved still name them.
+	for id, want := range map[Id]string{a: "/a", c: "/c", d: "/d"} {
+		if got := coll.Get(id).URI.Path; got != want {
+			t.Errorf("Get = %s, want %s", got, want)
+		}
+	}
+	if id, ok := coll.Lookup(mustParseURL("https://example.com/d")); !ok || id != d {
+		t.Errorf("Lookup(d) = %v, %v, want d", id, ok)
+	}
+	if _, ok := coll.Lookup(mustParseURL("https://example.com/b")); ok {
+		t.Error("Lookup found the removed URL")
+	}
+
+	neighbors := func(id Id) []Id {
+		var ids []Id
+		for n := range coll.Neighbors(id) {
+			ids = append(ids, n)
+		}
+		return ids
+	}
+	if got := neighbors(a); len(got) != 1 || got[0] != c {
+		t.Errorf("Neighbors(a) = %v, want [c]", got)
+	}
+	if got := neighbors(d); len(got) != 1 || got[0] != c {
+		t.Errorf("Neighbors(d) = %v, want [c]", got)
+	}
+
+	// A new entity gets a new Id, not that of the removed one.
+	if e := coll.Upsert(makeEntity("https://example.com/b")); e == b {
+		t.Error("Upsert reused a removed Id")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic on removed id")
+		}
+	}()
+	coll.Get(b)
+}
+
+func TestRemoveEdges(t *testing.T) {
+	coll := NewCollection()
+	a := coll.Upsert(makeEntity("https://example.com/a"))
+	b := coll.Upsert(makeEntity("https://example.com/b"))
+	c := coll.Upsert(makeEntity("https://example.com/c"))
+	coll.AddEdges(a, b)
+	coll.AddEdges(b, a)
+	coll.AddEdges(a, c)
+
+	if !coll.RemoveEdges(b, a) {
+		t.Error("RemoveEdges(b, a) = false, want true")
+	}
+	if coll.RemoveEdges(a, b) {
+		t.Error("RemoveEdges(a, b) again = true, want false")
+	}
+	for n := range coll.Neighbors(b) {
+		t.Errorf("Neighbors(b) yielded %v after RemoveEdges", n)
+	}
+	var count int
+	for range coll.Neighbors(a) {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("a has %d neighbors, want 1 (c)", count)
+	}
+}
@@ -0,0 +1,189 @@
+package types
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Diff reports how one collection differs from another, matching entities
+// by URL. Added and Removed list URLs; Changed lists the entities present in
+// both whose fields differ.
+type Diff struct {
+	Added   []string     `yaml:"added"   json:"added"`
+	Removed []string     `yaml:"removed" json:"removed"`
+	Changed []EntityDiff `yaml:"changed" json:"changed"`
+}
+
+// EntityDiff lists the changed fields of the entity at URI.
+type EntityDiff struct {
+	URI     string        `yaml:"uri"     json:"uri"`
+	Changes []FieldChange `yaml:"changes" json:"changes"`
+}
+
+// FieldChange reports the values a field lost and gained, rendered as
+// strings: times in RFC 3339, flags as "true", "false", or "contradicted" when
+// sources disagreed on them, edges as the URLs of neighbors, and links as
+// their kind and the URL of their target. A single-valued field that changed
+// loses its old value and gains its new one; one that was unset or became
+// unset has nothing on that side. Fields are named as in the serialized collection.
+type FieldChange struct {
+	Field   string   `yaml:"field"             json:"field"`
+	Removed []string `yaml:"removed,omitempty" json:"removed,omitempty"`
+	Added   []string `yaml:"added,omitempty"   json:"added,omitempty"`
+}
+
+// Empty reports whether the diff records no differences.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares c, the old collection, with other, the new one. Added URLs
+// are listed in the order of other, and removed and changed ones in the
+// order of c. Folders are not compared, as they record where an export
+// filed an entity rather than the entity itself.
+func (c *Collection) Diff(other *Collection) Diff {
+	d := Diff{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []EntityDiff{},
+	}
+
+	for i, entity := range c.entities {
+		j, ok := other.findIndex(entity.URI)
+		if !ok {
+			d.Removed = append(d.Removed, entity.URI.String())
+			continue
+		}
+
+		changes := diffEntities(entity, other.entities[j])
+		removed, added := diffValues(c.neighborURIs(uint(i)), other.neighborURIs(j))
+		if removed != nil || added != nil {
+			changes = append(changes, FieldChange{Field: "edges", Removed: removed, Added: added})
+		}
+		removed, added = diffValues(c.linkStrings(uint(i)), other.linkStrings(j))
+		if removed != nil || added != nil {
+			changes = append(changes, FieldChange{Field: "links", Removed: removed, Added: added})
+		}
+		if len(changes) > 0 {
+			d.Changed = append(d.Changed, EntityDiff{URI: entity.URI.String(), Changes: changes})
+		}
+	}
+
+	for _, entity := range other.entities {
+		if _, ok := c.findIndex(entity.URI); !ok {
+			d.Added = append(d.Added, entity.URI.String())
+		}
+	}
+
+	return d
+}
+
+// neighborURIs returns the sorted URLs of the neighbors of the entity at
+// index, each listed once.
+func (c *Collection) neighborURIs(index uint) []string {
+	uris := make([]string, 0, len(c.edges[index]))
+	for _, neighbor := range c.edges[index] {
+		uris = append(uris, c.entities[neighbor].URI.String())
+	}
+	slices.Sort(uris)
+	return slices.Compact(uris)
+}
+
+// linkStrings returns the sorted links from the entity at index, each
+// rendered as its kind and the URL of its target.
+func (c *Collection) linkStrings(index uint) []string {
+	links := make([]string, 0, len(c.links[index]))
+	for _, l := range c.links[index] {
+		links = append(links, string(l.kind)+" "+c.entities[l.to].URI.String())
+	}
+	slices.Sort(links)
+	return links
+}
+
+func diffEntities(before, after Entity) []FieldChange {
+	var changes []FieldChange
+
+	add := func(field string, from, to []string) {
+		removed, added := diffValues(from, to)
+		if removed != nil || added != nil {
+			changes = append(changes, FieldChange{Field: field, Removed: removed, Added: added})
+		}
+	}
+
+	add("createdAt", []string{formatDiffTime(time.Time(before.CreatedAt))}, []string{formatDiffTime(time.Time(after.CreatedAt))})
+	add("updatedAt", diffTimes(before.UpdatedAt), diffTimes(after.UpdatedAt))
+	add("names", MapToSortedSlice(before.Names), MapToSortedSlice(after.Names))
+	add("labels", MapToSortedSlice(before.Labels), MapToSortedSlice(after.Labels))
+	add("shared", diffFlag(before.Shared.optBool), diffFlag(after.Shared.optBool))
+	add("toRead", diffFlag(before.ToRead.optBool), diffFlag(after.ToRead.optBool))
+	add("isFeed", diffFlag(before.IsFeed.optBool), diffFlag(after.IsFeed.optBool))
+	add("extended", diffExtended(before.Extended), diffExtended(after.Extended))
+	add("lastVisitedAt", diffLastVisited(before.LastVisitedAt), diffLastVisited(after.LastVisitedAt))
+
+	return changes
+}
+
+// diffValues compares two lists of values as multisets, returning the values
+// only in before and those only in after, each in their original order, or
+// nil for both when the lists hold the same values.
+func diffValues(before, after []string) (removed, added []string) {
+	counts := make(map[string]int)
+	for _, v := range after {
+		counts[v]++
+	}
+
+	for _, v := range before {
+		if counts[v] > 0 {
+			counts[v]--
+		} else {
+			removed = append(removed, v)
+		}
+	}
+
+	for _, v := range after {
+		if counts[v] > 0 {
+			counts[v]--
+			added = append(added, v)
+		}
+	}
+
+	return removed, added
+}
+
+func formatDiffTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func diffTimes(ts []UpdatedAt) []string {
+	values := make([]string, len(ts))
+	for i, t := range ts {
+		values[i] = formatDiffTime(time.Time(t))
+	}
+	return values
+}
+
+func diffFlag(o optBool) []string {
+	if o.value.IsContradicted() {
+		return []string{"contradicted"}
+	}
+	if b, ok := o.get(); ok {
+		return []string{strconv.FormatBool(b)}
+	}
+	return nil
+}
+
+func diffExtended(es []Extended) []string {
+	values := make([]string, len(es))
+	for i, e := range es {
+		values[i] = string(e)
+	}
+	return values
+}
+
+func diffLastVisited(l LastVisitedAt) []string {
+	if t, ok := l.Get(); ok {
+		return []string{formatDiffTime(t)}
+	}
+	return nil
+}
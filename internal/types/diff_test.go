@@ -0,0 +1,76 @@
+package types
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	before := NewCollection()
+	a := makeEntity("https://example.com/a")
+	a.Labels[Label("go")] = struct{}{}
+	a.ToRead = NewToRead(true)
+	aID := before.Upsert(a)
+	bID := before.Upsert(makeEntity("https://example.com/b"))
+	before.AddEdges(aID, bID)
+	before.Upsert(makeEntity("https://example.com/gone"))
+
+	after := NewCollection()
+	a2 := makeEntity("https://example.com/a")
+	a2.Labels[Label("golang")] = struct{}{}
+	a2.ToRead = NewToRead(false)
+	a2.Extended = []Extended{"notes"}
+	a2.CreatedAt = CreatedAt(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	after.Upsert(a2)
+	after.Upsert(makeEntity("https://example.com/b"))
+	after.Upsert(makeEntity("https://example.com/new"))
+
+	d := before.Diff(&after)
+
+	if !slices.Equal(d.Added, []string{"https://example.com/new"}) {
+		t.Errorf("Added = %v", d.Added)
+	}
+	if !slices.Equal(d.Removed, []string{"https://example.com/gone"}) {
+		t.Errorf("Removed = %v", d.Removed)
+	}
+	if len(d.Changed) != 2 {
+		t.Fatalf("Changed = %+v, want a and b", d.Changed)
+	}
+
+	changes := make(map[string]FieldChange)
+	for _, c := range d.Changed[0].Changes {
+		changes[c.Field] = c
+	}
+	want := map[string]FieldChange{
+		"createdAt": {Field: "createdAt", Removed: []string{"0001-01-01T00:00:00Z"}, Added: []string{"2024-01-02T03:04:05Z"}},
+		"labels":    {Field: "labels", Removed: []string{"go"}, Added: []string{"golang"}},
+		"toRead":    {Field: "toRead", Removed: []string{"true"}, Added: []string{"false"}},
+		"extended":  {Field: "extended", Added: []string{"notes"}},
+		"edges":     {Field: "edges", Removed: []string{"https://example.com/b"}},
+	}
+	if d.Changed[0].URI != "https://example.com/a" || len(changes) != len(want) {
+		t.Fatalf("Changed[0] = %+v", d.Changed[0])
+	}
+	for field, w := range want {
+		got := changes[field]
+		if !slices.Equal(got.Removed, w.Removed) || !slices.Equal(got.Added, w.Added) {
+			t.Errorf("%s: got %+v, want %+v", field, got, w)
+		}
+	}
+
+	if d.Changed[1].URI != "https://example.com/b" || len(d.Changed[1].Changes) != 1 || d.Changed[1].Changes[0].Field != "edges" {
+		t.Errorf("Changed[1] = %+v, want only edges", d.Changed[1])
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	coll := NewCollection()
+	a := coll.Upsert(makeEntity("https://example.com/a"))
+	b := coll.Upsert(makeEntity("https://example.com/b"))
+	coll.AddEdges(a, b)
+
+	if d := coll.Diff(&coll); !d.Empty() {
+		t.Errorf("Diff with itself = %+v, want empty", d)
+	}
+}
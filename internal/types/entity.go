@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/henrytill/hbt-go/internal/belnap"
 	"github.com/henrytill/hbt-go/internal/pinboard"
 )
 
@@ -16,32 +17,49 @@ type Name string
 type Label string
 type Extended string
 
-// optBool is a tri-state bool: unset (the zero value), false, or true.
-// It is the shared implementation behind Shared, ToRead, and IsFeed, which
-// stay distinct types so Entity fields cannot be mixed up.
+// optBool is a flag in Belnap's four-valued logic: unset (Unknown, the zero
+// value), false, true, or contradicted (Both) when sources disagree. It is
+// the shared implementation behind Shared, ToRead, and IsFeed, which stay
+// distinct types so Entity fields cannot be mixed up.
 type optBool struct {
-	Bool  bool
-	Valid bool
+	value belnap.Value
 }
 
 func newOptBool(b bool) optBool {
-	return optBool{Bool: b, Valid: true}
+	if b {
+		return optBool{belnap.True}
+	}
+	return optBool{belnap.False}
 }
 
+// get returns the flag and whether it is set. A contradicted flag is
+// reported as unset; callers that must tell the two apart use Value.
 func (o optBool) get() (bool, bool) {
-	return o.Bool, o.Valid
+	return o.value.ToBool()
 }
 
 // merge combines two values: an unset side yields the other, and two set
-// values OR together.
+// values that disagree are contradicted.
 func (o optBool) merge(p optBool) optBool {
-	if !o.Valid {
-		return p
-	}
-	if !p.Valid {
-		return o
+	return optBool{o.value.Merge(p.value)}
+}
+
+// resolve replaces a contradicted value by policy, reporting false if the
+// policy refuses to.
+func (o optBool) resolve(policy ContradictionPolicy) (optBool, bool) {
+	if !o.value.IsContradicted() {
+		return o, true
+	}
+	switch policy {
+	case ContradictionTrue:
+		return newOptBool(true), true
+	case ContradictionUnset:
+		return optBool{}, true
+	case ContradictionRefuse:
+		return o, false
+	default:
+		return newOptBool(false), true
 	}
-	return optBool{Bool: o.Bool || p.Bool, Valid: true}
 }
 
 type Shared struct{ optBool }
@@ -50,6 +68,8 @@ func NewShared(b bool) Shared { return Shared{newOptBool(b)} }
 
 func (s Shared) Get() (bool, bool) { return s.get() }
 
+func (s Shared) Value() belnap.Value { return s.value }
+
 func (s Shared) Merge(t Shared) Shared { return Shared{s.merge(t.optBool)} }
 
 type ToRead struct{ optBool }
@@ -58,6 +78,8 @@ func NewToRead(b bool) ToRead { return ToRead{newOptBool(b)} }
 
 func (r ToRead) Get() (bool, bool) { return r.get() }
 
+func (r ToRead) Value() belnap.Value { return r.value }
+
 func (r ToRead) Merge(s ToRead) ToRead { return ToRead{r.merge(s.optBool)} }
 
 type IsFeed struct{ optBool }
@@ -66,8 +88,45 @@ func NewIsFeed(b bool) IsFeed { return IsFeed{newOptBool(b)} }
 
 func (f IsFeed) Get() (bool, bool) { return f.get() }
 
+func (f IsFeed) Value() belnap.Value { return f.value }
+
 func (f IsFeed) Merge(g IsFeed) IsFeed { return IsFeed{f.merge(g.optBool)} }
 
+// ContradictionPolicy chooses how a flag contradicted by its sources is
+// written to a format, which has no way to record a contradiction.
+type ContradictionPolicy string
+
+const (
+	// ContradictionFalse writes a contradicted flag as false, so an entity
+	// some source keeps private is not published. It is the default.
+	ContradictionFalse ContradictionPolicy = "false"
+	// ContradictionTrue writes a contradicted flag as true, as values were
+	// merged before contradictions were tracked.
+	ContradictionTrue ContradictionPolicy = "true"
+	// ContradictionUnset writes a contradicted flag as unset.
+	ContradictionUnset ContradictionPolicy = "unset"
+	// ContradictionRefuse refuses to write an entity with a contradicted
+	// flag.
+	ContradictionRefuse ContradictionPolicy = "refuse"
+)
+
+// ParseContradictionPolicy parses the name of a policy, where the empty
+// string means ContradictionFalse.
+func ParseContradictionPolicy(s string) (ContradictionPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", string(ContradictionFalse):
+		return ContradictionFalse, nil
+	case string(ContradictionTrue):
+		return ContradictionTrue, nil
+	case string(ContradictionUnset):
+		return ContradictionUnset, nil
+	case string(ContradictionRefuse):
+		return ContradictionRefuse, nil
+	default:
+		return ContradictionFalse, fmt.Errorf("invalid contradiction policy %q: expected false, true, unset, or refuse", s)
+	}
+}
+
 type CreatedAt time.Time
 
 func (c CreatedAt) Unix() int64 {
@@ -126,6 +185,24 @@ func (l LastVisitedAt) Merge(m LastVisitedAt) LastVisitedAt {
 	return l
 }
 
+// Folder is one level of the folder hierarchy a bookmark was filed under in
+// a browser export. AddDate and LastModified are zero when the export did not
+// record them.
+type Folder struct {
+	Name         string
+	AddDate      time.Time
+	LastModified time.Time
+}
+
+// FolderPath lists the folders enclosing a bookmark, outermost first.
+type FolderPath []Folder
+
+// Equal reports whether p and q name the same folders; folder dates are
+// ignored.
+func (p FolderPath) Equal(q FolderPath) bool {
+	return slices.EqualFunc(p, q, func(a, b Folder) bool { return a.Name == b.Name })
+}
+
 type Entity struct {
 	URI           *url.URL
 	CreatedAt     CreatedAt
@@ -137,11 +214,20 @@ type Entity struct {
 	IsFeed        IsFeed
 	Extended      []Extended
 	LastVisitedAt LastVisitedAt
+	// Folders holds each folder path the entity was found under. It is kept
+	// in memory only, for formatters that rebuild a hierarchy, and is not
+	// part of the serialized representation.
+	Folders []FolderPath
+	// Provenance lists the values each source gave the entity, when sources
+	// are recorded with RecordSource. It is nil otherwise.
+	Provenance []Contribution
 }
 
 // Equal reports whether e and other carry the same data. Times compare by
 // instant rather than by representation, Names and Labels by set membership,
-// and UpdatedAt and Extended element by element.
+// UpdatedAt and Extended element by element, and Folders by folder names.
+// Provenance records where the data came from rather than the data itself,
+// and is not compared.
 func (e Entity) Equal(other Entity) bool {
 	if (e.URI == nil) != (other.URI == nil) {
 		return false
@@ -165,12 +251,20 @@ func (e Entity) Equal(other Entity) bool {
 	if !slices.Equal(e.Extended, other.Extended) {
 		return false
 	}
-	return e.LastVisitedAt.Equal(other.LastVisitedAt)
+	if !e.LastVisitedAt.Equal(other.LastVisitedAt) {
+		return false
+	}
+	return slices.EqualFunc(e.Folders, other.Folders, FolderPath.Equal)
 }
 
 // absorb merges other into e. The two behaviors commented below are shared with
 // hbt-ocaml and hbt-rs, settled in #57 and pinned by fixtures in hbt-data.
 func (e *Entity) absorb(other Entity) {
+	// An identical entity still contributes its source.
+	if len(other.Provenance) > 0 {
+		e.Provenance = mergeProvenance(e.Provenance, other.Provenance)
+	}
+
 	// Absorbing an identical entity is a no-op: without the guard, a bookmark
 	// repeated in the input would accumulate a copy of its description per
 	// occurrence. See the bookmarks_repeated fixture.
@@ -212,6 +306,66 @@ func (e *Entity) absorb(other Entity) {
 	e.Extended = append(e.Extended, other.Extended...)
 
 	e.LastVisitedAt = e.LastVisitedAt.Merge(other.LastVisitedAt)
+
+	for _, path := range other.Folders {
+		if !slices.ContainsFunc(e.Folders, path.Equal) {
+			e.Folders = append(e.Folders, path)
+		}
+	}
+}
+
+// Contradictions returns the names of the flags of e that its sources
+// disagree on, as they are named in the serialized collection.
+func (e Entity) Contradictions() []string {
+	var fields []string
+	for _, flag := range []struct {
+		name  string
+		value optBool
+	}{
+		{"shared", e.Shared.optBool},
+		{"toRead", e.ToRead.optBool},
+		{"isFeed", e.IsFeed.optBool},
+	} {
+		if flag.value.value.IsContradicted() {
+			fields = append(fields, flag.name)
+		}
+	}
+	return fields
+}
+
+func (e Entity) isContradicted() bool {
+	return len(e.Contradictions()) > 0
+}
+
+// ResolveContradictions replaces the contradicted flags of e by policy. Under
+// ContradictionRefuse it returns an error naming them instead.
+func (e *Entity) ResolveContradictions(policy ContradictionPolicy) error {
+	shared, sharedOK := e.Shared.resolve(policy)
+	toRead, toReadOK := e.ToRead.resolve(policy)
+	isFeed, isFeedOK := e.IsFeed.resolve(policy)
+	if !sharedOK || !toReadOK || !isFeedOK {
+		return fmt.Errorf("%s: sources disagree on %s", e.URI, strings.Join(e.Contradictions(), ", "))
+	}
+	e.Shared = Shared{shared}
+	e.ToRead = ToRead{toRead}
+	e.IsFeed = IsFeed{isFeed}
+	return nil
+}
+
+// ApplyMappings replaces each label of e that is a key of mappings with the
+// label it maps to.
+func (e *Entity) ApplyMappings(mappings map[string]string) {
+	newLabels := make(map[Label]struct{})
+
+	for label := range e.Labels {
+		if newLabel, exists := mappings[string(label)]; exists {
+			newLabels[Label(newLabel)] = struct{}{}
+		} else {
+			newLabels[label] = struct{}{}
+		}
+	}
+
+	e.Labels = newLabels
 }
 
 type entityRepr struct {
@@ -225,6 +379,63 @@ type entityRepr struct {
 	IsFeed        *bool    `yaml:"isFeed,omitempty"        json:"isFeed,omitempty"`
 	Extended      []string `yaml:"extended,omitempty"      json:"extended,omitempty"`
 	LastVisitedAt *int64   `yaml:"lastVisitedAt,omitempty" json:"lastVisitedAt,omitempty"`
+	// Provenance requires version 0.2.
+	Provenance []contributionRepr `yaml:"provenance,omitempty" json:"provenance,omitempty"`
+	// Contradicted names the flags sources disagree on, which are left out
+	// of shared, toRead, and isFeed. It requires version 0.4.
+	Contradicted []string `yaml:"contradicted,omitempty" json:"contradicted,omitempty"`
+	// Folders requires version 0.5.
+	Folders [][]folderRepr `yaml:"folders,omitempty" json:"folders,omitempty"`
+}
+
+// folderRepr is a Folder, with dates in Unix seconds that are omitted when
+// unknown.
+type folderRepr struct {
+	Name         string `yaml:"name"                   json:"name"`
+	AddDate      int64  `yaml:"addDate,omitempty"      json:"addDate,omitempty"`
+	LastModified int64  `yaml:"lastModified,omitempty" json:"lastModified,omitempty"`
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeOrZero(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
+
+func foldersToRepr(paths []FolderPath) [][]folderRepr {
+	if len(paths) == 0 {
+		return nil
+	}
+	reprs := make([][]folderRepr, len(paths))
+	for i, path := range paths {
+		reprs[i] = make([]folderRepr, len(path))
+		for j, f := range path {
+			reprs[i][j] = folderRepr{Name: f.Name, AddDate: unixOrZero(f.AddDate), LastModified: unixOrZero(f.LastModified)}
+		}
+	}
+	return reprs
+}
+
+func foldersFromRepr(reprs [][]folderRepr) []FolderPath {
+	if len(reprs) == 0 {
+		return nil
+	}
+	paths := make([]FolderPath, len(reprs))
+	for i, repr := range reprs {
+		paths[i] = make(FolderPath, len(repr))
+		for j, f := range repr {
+			paths[i][j] = Folder{Name: f.Name, AddDate: timeOrZero(f.AddDate), LastModified: timeOrZero(f.LastModified)}
+		}
+	}
+	return paths
 }
 
 func MapToSortedSlice[K ~string](m map[K]struct{}) []string {
@@ -300,6 +511,9 @@ func (e Entity) toRepr() entityRepr {
 		IsFeed:        isFeed,
 		Extended:      extended,
 		LastVisitedAt: lastVisitedAt,
+		Provenance:    provenanceToRepr(e.Provenance),
+		Contradicted:  e.Contradictions(),
+		Folders:       foldersToRepr(e.Folders),
 	}
 }
 
@@ -356,6 +570,23 @@ func (e *Entity) fromRepr(s entityRepr) error {
 		e.Extended = nil
 	}
 
+	e.Provenance = provenanceFromRepr(s.Provenance)
+	e.Folders = foldersFromRepr(s.Folders)
+
+	for _, name := range s.Contradicted {
+		contradicted := optBool{belnap.Both}
+		switch name {
+		case "shared":
+			e.Shared = Shared{contradicted}
+		case "toRead":
+			e.ToRead = ToRead{contradicted}
+		case "isFeed":
+			e.IsFeed = IsFeed{contradicted}
+		default:
+			return fmt.Errorf("unknown contradicted flag %q", name)
+		}
+	}
+
 	return nil
 }
 
@@ -405,3 +636,59 @@ func NewEntityFromPost(p pinboard.Post) (Entity, error) {
 
 	return entity, nil
 }
+
+func yesOrNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// pinboardTags joins labels into the space-separated tags of a post. Spaces
+// within a label become underscores, as they would otherwise split it into
+// several tags.
+func pinboardTags(labels map[Label]struct{}) string {
+	tags := make([]string, 0, len(labels))
+	for label := range labels {
+		if tag := strings.Join(strings.Fields(string(label)), "_"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+	return strings.Join(slices.Compact(tags), " ")
+}
+
+// NewPostFromEntity is the inverse of NewEntityFromPost. A post holds a
+// single description and extended note, so only the first name in sorted
+// order and the first Extended are kept. Labels become tags as pinboardTags
+// describes. Unset Shared and ToRead are written as "no", so an entity of
+// unknown visibility is not published.
+func NewPostFromEntity(e Entity) pinboard.Post {
+	var href string
+	if e.URI != nil {
+		href = e.URI.String()
+	}
+
+	var description string
+	if names := MapToSortedSlice(e.Names); len(names) > 0 {
+		description = names[0]
+	}
+
+	var extended string
+	if len(e.Extended) > 0 {
+		extended = string(e.Extended[0])
+	}
+
+	shared, _ := e.Shared.Get()
+	toRead, _ := e.ToRead.Get()
+
+	return pinboard.Post{
+		Href:        href,
+		Time:        time.Time(e.CreatedAt).UTC().Format(time.RFC3339),
+		Description: description,
+		Extended:    extended,
+		Tags:        pinboardTags(e.Labels),
+		Shared:      yesOrNo(shared),
+		ToRead:      yesOrNo(toRead),
+	}
+}
@@ -1,10 +1,13 @@
 package types
 
 import (
+	"encoding/json"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/henrytill/hbt-go/internal/belnap"
 	"github.com/henrytill/hbt-go/internal/pinboard"
 )
 
@@ -12,6 +15,7 @@ func TestSharedMerge(t *testing.T) {
 	unset := Shared{}
 	no := NewShared(false)
 	yes := NewShared(true)
+	both := Shared{optBool{belnap.Both}}
 
 	tests := []struct {
 		name string
@@ -22,11 +26,12 @@ func TestSharedMerge(t *testing.T) {
 		{"unset absorbs other false", unset, no, no},
 		{"set keeps value over unset", yes, unset, yes},
 		{"false keeps false over unset", no, unset, no},
-		{"false or false", no, no, no},
-		{"false or true", no, yes, yes},
-		{"true or false", yes, no, yes},
-		{"true or true", yes, yes, yes},
+		{"false and false agree", no, no, no},
+		{"false and true contradict", no, yes, both},
+		{"true and false contradict", yes, no, both},
+		{"true and true agree", yes, yes, yes},
 		{"both unset", unset, unset, unset},
+		{"contradiction persists", both, yes, both},
 	}
 
 	for _, tt := range tests {
@@ -37,8 +42,8 @@ func TestSharedMerge(t *testing.T) {
 }
 
 func TestToReadMerge(t *testing.T) {
-	if got := NewToRead(false).Merge(NewToRead(true)); got != NewToRead(true) {
-		t.Errorf("false.Merge(true) = %v, want true", got)
+	if got := NewToRead(false).Merge(NewToRead(true)); got.Value() != belnap.Both {
+		t.Errorf("false.Merge(true) = %v, want contradicted", got)
 	}
 	if got := (ToRead{}).Merge(NewToRead(false)); got != NewToRead(false) {
 		t.Errorf("unset.Merge(false) = %v, want false", got)
@@ -46,8 +51,8 @@ func TestToReadMerge(t *testing.T) {
 }
 
 func TestIsFeedMerge(t *testing.T) {
-	if got := NewIsFeed(true).Merge(NewIsFeed(false)); got != NewIsFeed(true) {
-		t.Errorf("true.Merge(false) = %v, want true", got)
+	if got := NewIsFeed(true).Merge(NewIsFeed(false)); got.Value() != belnap.Both {
+		t.Errorf("true.Merge(false) = %v, want contradicted", got)
 	}
 	if got := (IsFeed{}).Merge(IsFeed{}); got != (IsFeed{}) {
 		t.Errorf("unset.Merge(unset) = %v, want unset", got)
@@ -140,8 +145,8 @@ func TestUpsertMergesSameURI(t *testing.T) {
 	if labels := MapToSortedSlice(got.Labels); !slices.Equal(labels, []string{"a", "b"}) {
 		t.Errorf("Labels = %v, want union [a b]", labels)
 	}
-	if s, ok := got.Shared.Get(); !ok || !s {
-		t.Errorf("Shared = (%v, %v), want (true, true): valid values OR together", s, ok)
+	if got.Shared.Value() != belnap.Both {
+		t.Errorf("Shared = %v, want contradicted: the sources disagree", got.Shared.Value())
 	}
 	if tr, ok := got.ToRead.Get(); !ok || !tr {
 		t.Errorf("ToRead = (%v, %v), want (true, true): set value wins over unset", tr, ok)
@@ -359,6 +364,68 @@ func TestNewEntityFromPost(t *testing.T) {
 	})
 }
 
+func TestNewPostFromEntity(t *testing.T) {
+	t.Run("full entity", func(t *testing.T) {
+		post := NewPostFromEntity(Entity{
+			URI:       mustParseURL("https://example.com/"),
+			CreatedAt: CreatedAt(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Names:     map[Name]struct{}{"B": {}, "A": {}},
+			Labels:    map[Label]struct{}{"web": {}, "go": {}},
+			Shared:    NewShared(true),
+			ToRead:    NewToRead(false),
+			Extended:  []Extended{"first", "second"},
+		})
+
+		want := pinboard.Post{
+			Href:        "https://example.com/",
+			Time:        "2021-01-01T00:00:00Z",
+			Description: "A",
+			Extended:    "first",
+			Tags:        "go web",
+			Shared:      "yes",
+			ToRead:      "no",
+		}
+		if post != want {
+			t.Errorf("got %+v, want %+v", post, want)
+		}
+	})
+
+	t.Run("spaces in labels become underscores", func(t *testing.T) {
+		e := makeEntity("https://example.com/")
+		e.Labels = map[Label]struct{}{"machine learning": {}, "machine_learning": {}, "go": {}, "  padded\tlabel ": {}}
+		post := NewPostFromEntity(e)
+		if want := "go machine_learning padded_label"; post.Tags != want {
+			t.Errorf("Tags = %q, want %q", post.Tags, want)
+		}
+	})
+
+	t.Run("unset booleans are written as no", func(t *testing.T) {
+		post := NewPostFromEntity(makeEntity("https://example.com/"))
+		if post.Shared != "no" || post.ToRead != "no" {
+			t.Errorf("Shared = %q, ToRead = %q, want no and no", post.Shared, post.ToRead)
+		}
+	})
+
+	t.Run("round trips through NewEntityFromPost", func(t *testing.T) {
+		original := pinboard.Post{
+			Href:        "https://example.com/",
+			Time:        "2021-01-01T12:30:00Z",
+			Description: "Example",
+			Extended:    "notes",
+			Tags:        "go web",
+			Shared:      "no",
+			ToRead:      "yes",
+		}
+		entity, err := NewEntityFromPost(original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post := NewPostFromEntity(entity); post != original {
+			t.Errorf("got %+v, want %+v", post, original)
+		}
+	})
+}
+
 func TestVersion(t *testing.T) {
 	t.Run("accepts with and without v prefix", func(t *testing.T) {
 		for _, s := range []string{"0.1.0", "v0.1.0"} {
@@ -380,14 +447,19 @@ func TestVersion(t *testing.T) {
 		}
 	})
 
-	t.Run("compatibility is major.minor", func(t *testing.T) {
+	t.Run("compatibility is a range of major.minor", func(t *testing.T) {
 		tests := []struct {
 			version string
 			want    bool
 		}{
+			{"v0.0.9", false},
 			{"v0.1.0", true},
 			{"v0.1.9", true},
-			{"v0.2.0", false},
+			{"v0.2.0", true},
+			{"v0.3.0", true},
+			{"v0.4.0", true},
+			{"v0.5.0", true},
+			{"v0.6.0", false},
 			{"v1.1.0", false},
 		}
 		for _, tt := range tests {
@@ -401,3 +473,101 @@ func TestVersion(t *testing.T) {
 		}
 	})
 }
+
+func TestResolveContradictions(t *testing.T) {
+	contradicted := entityAt("https://example.com/", 100)
+	contradicted.Shared = NewShared(true).Merge(NewShared(false))
+	contradicted.ToRead = NewToRead(true)
+
+	if got := contradicted.Contradictions(); !slices.Equal(got, []string{"shared"}) {
+		t.Fatalf("Contradictions = %v, want [shared]", got)
+	}
+
+	tests := []struct {
+		policy ContradictionPolicy
+		want   Shared
+	}{
+		{ContradictionFalse, NewShared(false)},
+		{ContradictionTrue, NewShared(true)},
+		{ContradictionUnset, Shared{}},
+	}
+	for _, tt := range tests {
+		e := contradicted
+		if err := e.ResolveContradictions(tt.policy); err != nil {
+			t.Errorf("%s: %v", tt.policy, err)
+			continue
+		}
+		if e.Shared != tt.want || e.ToRead != NewToRead(true) {
+			t.Errorf("%s: Shared = %v, ToRead = %v", tt.policy, e.Shared, e.ToRead)
+		}
+	}
+
+	e := contradicted
+	if err := e.ResolveContradictions(ContradictionRefuse); err == nil {
+		t.Error("refuse: expected an error")
+	}
+	if e.Shared.Value() != belnap.Both {
+		t.Error("refuse: the entity should be left as it was")
+	}
+}
+
+func TestContradictionsRoundTrip(t *testing.T) {
+	contradicted := entityAt("https://example.com/", 100)
+	contradicted.Shared = NewShared(true).Merge(NewShared(false))
+	contradicted.IsFeed = NewIsFeed(false)
+
+	coll := NewCollection()
+	coll.Upsert(contradicted)
+	data, err := json.Marshal(&coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":"0.4.0"`) || !strings.Contains(string(data), `"contradicted":["shared"]`) {
+		t.Errorf("contradicted collection written as:\n%s", data)
+	}
+
+	var got Collection
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	e := got.entities[0]
+	if e.Shared.Value() != belnap.Both || e.IsFeed != NewIsFeed(false) {
+		t.Errorf("after round trip Shared = %v, IsFeed = %v", e.Shared, e.IsFeed)
+	}
+
+	bad := strings.Replace(string(data), `"contradicted":["shared"]`, `"contradicted":["starred"]`, 1)
+	if err := json.Unmarshal([]byte(bad), &got); err == nil {
+		t.Error("expected an error for an unknown contradicted flag")
+	}
+}
+
+func TestFoldersRoundTrip(t *testing.T) {
+	filed := entityAt("https://example.com/", 100)
+	filed.Folders = []FolderPath{
+		{{Name: "Bookmarks Menu", AddDate: time.Unix(50, 0)}, {Name: "Go", LastModified: time.Unix(60, 0)}},
+		{{Name: "Toolbar"}},
+	}
+
+	coll := NewCollection()
+	coll.Upsert(filed)
+	data, err := json.Marshal(&coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":"0.5.0"`) {
+		t.Errorf("collection with folders not written as 0.5.0:\n%s", data)
+	}
+
+	var got Collection
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	e := got.entities[0]
+	if !e.Equal(filed) {
+		t.Errorf("folders changed in round trip: %+v", e.Folders)
+	}
+	if first := e.Folders[0]; !first[0].AddDate.Equal(time.Unix(50, 0)) || !first[0].LastModified.IsZero() ||
+		!first[1].LastModified.Equal(time.Unix(60, 0)) {
+		t.Errorf("folder dates changed in round trip: %+v", first)
+	}
+}
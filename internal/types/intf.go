@@ -2,6 +2,7 @@ package types
 
 import (
 	"io"
+	"iter"
 )
 
 type Parser interface {
@@ -11,3 +12,18 @@ type Parser interface {
 type Formatter interface {
 	Format(w io.Writer, coll *Collection) error
 }
+
+// StreamParser is a Parser that can also yield the nodes of its input one at
+// a time, without building a collection. The iterator stops after yielding
+// an error.
+type StreamParser interface {
+	Parser
+	Stream(r io.Reader) iter.Seq2[Node, error]
+}
+
+// StreamFormatter is a Formatter that can also write nodes as they are
+// yielded, stopping at the first error.
+type StreamFormatter interface {
+	Formatter
+	FormatStream(w io.Writer, nodes iter.Seq2[Node, error]) error
+}
@@ -0,0 +1,215 @@
+package types
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// LinkKind gives the meaning of a link, read from its source to its target:
+// "a child-of b", "a cites b".
+type LinkKind string
+
+const (
+	// LinkChildOf links an entity to the one it is listed under.
+	LinkChildOf LinkKind = "child-of"
+	// LinkRelated links an entity to one it is related to.
+	LinkRelated LinkKind = "related"
+	// LinkDuplicateOf links an entity to one it duplicates.
+	LinkDuplicateOf LinkKind = "duplicate-of"
+	// LinkReplacedBy links an entity to the one that supersedes it.
+	LinkReplacedBy LinkKind = "replaced-by"
+	// LinkCites links an entity to one it cites.
+	LinkCites LinkKind = "cites"
+)
+
+// LinkKinds lists every kind of link.
+var LinkKinds = []LinkKind{LinkChildOf, LinkRelated, LinkDuplicateOf, LinkReplacedBy, LinkCites}
+
+// ParseLinkKind parses the name of a kind of link.
+func ParseLinkKind(s string) (LinkKind, error) {
+	kind := LinkKind(strings.ToLower(s))
+	if !slices.Contains(LinkKinds, kind) {
+		return "", fmt.Errorf("invalid link kind %q: expected child-of, related, duplicate-of, replaced-by, or cites", s)
+	}
+	return kind, nil
+}
+
+// link is a link held by its source, to the index of its target.
+type link struct {
+	to   uint
+	kind LinkKind
+}
+
+// Link adds a directed link of kind from one entity to another. Unlike the
+// edges of AddEdges, a link is held once, however many times it is added.
+func (c *Collection) Link(from, to Id, kind LinkKind) {
+	f, t := c.indexOf(from), c.indexOf(to)
+	c.addLink(f, link{to: t, kind: kind})
+}
+
+func (c *Collection) addLink(from uint, l link) {
+	if !slices.Contains(c.links[from], l) {
+		c.links[from] = append(c.links[from], l)
+	}
+}
+
+// Unlink removes the link of kind from one entity to another, and reports
+// whether there was one.
+func (c *Collection) Unlink(from, to Id, kind LinkKind) bool {
+	f, t := c.indexOf(from), c.indexOf(to)
+	l := link{to: t, kind: kind}
+	if !slices.Contains(c.links[f], l) {
+		return false
+	}
+	c.links[f] = slices.DeleteFunc(slices.Clone(c.links[f]), func(m link) bool { return m == l })
+	return true
+}
+
+// Links returns an iterator over the links from id, with their targets, in
+// the order they were added.
+func (c *Collection) Links(id Id) iter.Seq2[Id, LinkKind] {
+	from := c.indexOf(id)
+	return func(yield func(Id, LinkKind) bool) {
+		for _, l := range c.links[from] {
+			if !yield(c.idAt(l.to), l.kind) {
+				return
+			}
+		}
+	}
+}
+
+// hasLinks reports whether any entity of c links to another.
+func (c *Collection) hasLinks() bool {
+	return slices.ContainsFunc(c.links, func(links []link) bool { return len(links) > 0 })
+}
+
+// Parents returns the entities id is a child of, in the order the links
+// were added.
+func (c *Collection) Parents(id Id) []Id {
+	var parents []Id
+	for target, kind := range c.Links(id) {
+		if kind == LinkChildOf {
+			parents = append(parents, target)
+		}
+	}
+	return parents
+}
+
+// Children returns the entities that are children of id, in insertion
+// order.
+func (c *Collection) Children(id Id) []Id {
+	parent := c.indexOf(id)
+	var children []Id
+	for i, links := range c.links {
+		if slices.Contains(links, link{to: parent, kind: LinkChildOf}) {
+			children = append(children, c.idAt(uint(i)))
+		}
+	}
+	return children
+}
+
+// Ancestors returns the parents of id, their parents, and so on, nearest
+// first, each once. A cycle of child-of links ends the search where it
+// returns to an entity already found.
+func (c *Collection) Ancestors(id Id) []Id {
+	start := c.indexOf(id)
+	seen := map[uint]bool{start: true}
+	queue := []uint{start}
+	var ancestors []Id
+	for len(queue) > 0 {
+		index := queue[0]
+		queue = queue[1:]
+		for _, l := range c.links[index] {
+			if l.kind != LinkChildOf || seen[l.to] {
+				continue
+			}
+			seen[l.to] = true
+			queue = append(queue, l.to)
+			ancestors = append(ancestors, c.idAt(l.to))
+		}
+	}
+	return ancestors
+}
+
+// Components returns the connected components of c, joining entities by
+// edges and by links of any kind in either direction. Each component is in
+// insertion order, and the components in the order of their first
+// entities. An entity with neither edges nor links is a component of its
+// own.
+func (c *Collection) Components() [][]Id {
+	// Union-find over indices, each root being the earliest index of its
+	// component.
+	parent := make([]uint, len(c.entities))
+	for i := range parent {
+		parent[i] = uint(i)
+	}
+	var find func(uint) uint
+	find = func(i uint) uint {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j uint) {
+		i, j = find(i), find(j)
+		if i > j {
+			i, j = j, i
+		}
+		parent[j] = i
+	}
+
+	for i := range c.entities {
+		for _, to := range c.edges[i] {
+			union(uint(i), to)
+		}
+		for _, l := range c.links[i] {
+			union(uint(i), l.to)
+		}
+	}
+
+	var components [][]Id
+	position := make(map[uint]int)
+	for i := range c.entities {
+		root := find(uint(i))
+		p, ok := position[root]
+		if !ok {
+			p = len(components)
+			position[root] = p
+			components = append(components, nil)
+		}
+		components[p] = append(components[p], c.idAt(uint(i)))
+	}
+	return components
+}
+
+type linkRepr struct {
+	To   uint     `yaml:"to"   json:"to"`
+	Kind LinkKind `yaml:"kind" json:"kind"`
+}
+
+func linksToRepr(links []link) []linkRepr {
+	if len(links) == 0 {
+		return nil
+	}
+	reprs := make([]linkRepr, len(links))
+	for i, l := range links {
+		reprs[i] = linkRepr{To: l.to, Kind: l.kind}
+	}
+	return reprs
+}
+
+func linksFromRepr(reprs []linkRepr, length int) ([]link, error) {
+	links := make([]link, 0, len(reprs))
+	for _, r := range reprs {
+		if r.To >= uint(length) {
+			return nil, fmt.Errorf("link %d out of range [0, %d)", r.To, length)
+		}
+		if _, err := ParseLinkKind(string(r.Kind)); err != nil {
+			return nil, err
+		}
+		links = append(links, link{to: r.To, kind: r.Kind})
+	}
+	return links, nil
+}
@@ -0,0 +1,179 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// linkTestCollection holds a tree, a -> b -> c by child-of links, with d
+// citing c, and e apart.
+func linkTestCollection(coll *Collection) map[string]Id {
+	*coll = NewCollection()
+	ids := make(map[string]Id)
+	for _, path := range []string{"a", "b", "c", "d", "e"} {
+		ids[path] = coll.Upsert(makeEntity("https://example.com/" + path))
+	}
+	coll.Link(ids["b"], ids["a"], LinkChildOf)
+	coll.Link(ids["c"], ids["b"], LinkChildOf)
+	coll.Link(ids["d"], ids["c"], LinkCites)
+	return ids
+}
+
+func linkPaths(coll *Collection, ids []Id) []string {
+	var paths []string
+	for _, id := range ids {
+		paths = append(paths, strings.TrimPrefix(coll.Get(id).URI.Path, "/"))
+	}
+	return paths
+}
+
+func TestLinks(t *testing.T) {
+	var coll Collection
+	ids := linkTestCollection(&coll)
+
+	coll.Link(ids["c"], ids["b"], LinkChildOf)
+	var links []string
+	for target, kind := range coll.Links(ids["c"]) {
+		links = append(links, string(kind)+" "+linkPaths(&coll, []Id{target})[0])
+	}
+	if !slices.Equal(links, []string{"child-of b"}) {
+		t.Errorf("Links(c) = %v, want the link once", links)
+	}
+
+	if got := linkPaths(&coll, coll.Parents(ids["c"])); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("Parents(c) = %v", got)
+	}
+	if got := linkPaths(&coll, coll.Children(ids["b"])); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("Children(b) = %v", got)
+	}
+	if got := coll.Children(ids["c"]); len(got) != 0 {
+		t.Errorf("Children(c) = %v, want none: d cites c", got)
+	}
+	if got := linkPaths(&coll, coll.Ancestors(ids["c"])); !slices.Equal(got, []string{"b", "a"}) {
+		t.Errorf("Ancestors(c) = %v, want [b a]", got)
+	}
+
+	// A cycle ends the search.
+	coll.Link(ids["a"], ids["c"], LinkChildOf)
+	if got := linkPaths(&coll, coll.Ancestors(ids["c"])); !slices.Equal(got, []string{"b", "a"}) {
+		t.Errorf("Ancestors(c) in a cycle = %v, want [b a]", got)
+	}
+
+	if !coll.Unlink(ids["a"], ids["c"], LinkChildOf) || coll.Unlink(ids["a"], ids["c"], LinkChildOf) {
+		t.Error("Unlink should report the link once")
+	}
+}
+
+func TestComponents(t *testing.T) {
+	var coll Collection
+	ids := linkTestCollection(&coll)
+	f := coll.Upsert(makeEntity("https://example.com/f"))
+	coll.AddEdges(f, ids["e"])
+
+	var got [][]string
+	for _, component := range coll.Components() {
+		got = append(got, linkPaths(&coll, component))
+	}
+	want := [][]string{{"a", "b", "c", "d"}, {"e", "f"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Components = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveRewritesLinks(t *testing.T) {
+	var coll Collection
+	ids := linkTestCollection(&coll)
+
+	coll.Remove(ids["b"])
+	if got := coll.Parents(ids["c"]); len(got) != 0 {
+		t.Errorf("Parents(c) after removing b = %v", linkPaths(&coll, got))
+	}
+	var targets []Id
+	for target := range coll.Links(ids["d"]) {
+		targets = append(targets, target)
+	}
+	if len(targets) != 1 || targets[0] != ids["c"] {
+		t.Errorf("Links(d) after Remove = %v, want [c]", linkPaths(&coll, targets))
+	}
+}
+
+func TestLinksRoundTrip(t *testing.T) {
+	var coll Collection
+	linkTestCollection(&coll)
+
+	data, err := json.Marshal(&coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":"0.3.0"`) || !strings.Contains(string(data), `"links":[{"to":0,"kind":"child-of"}]`) {
+		t.Errorf("unexpected serialization:\n%s", data)
+	}
+
+	var got Collection
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	c, _ := got.Lookup(mustParseURL("https://example.com/c"))
+	if paths := linkPaths(&got, got.Ancestors(c)); !slices.Equal(paths, []string{"b", "a"}) {
+		t.Errorf("Ancestors(c) after round trip = %v", paths)
+	}
+
+	collected, err := Collect(got.Stream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := got.Diff(&collected); !d.Empty() {
+		t.Errorf("Collect(Stream()) lost links: %+v", d)
+	}
+
+	bad := strings.Replace(string(data), `"kind":"cites"`, `"kind":"likes"`, 1)
+	if err := json.Unmarshal([]byte(bad), &got); err == nil || !strings.Contains(err.Error(), "invalid link kind") {
+		t.Errorf("Unmarshal with an unknown kind: %v", err)
+	}
+}
+
+func TestDiffAndMergeLinks(t *testing.T) {
+	var base, ours, theirs Collection
+	linkTestCollection(&base)
+	ourIDs := linkTestCollection(&ours)
+	ours.Unlink(ourIDs["d"], ourIDs["c"], LinkCites)
+	theirIDs := linkTestCollection(&theirs)
+	theirs.Link(theirIDs["e"], theirIDs["a"], LinkRelated)
+
+	d := base.Diff(&ours)
+	want := FieldChange{Field: "links", Removed: []string{"cites https://example.com/c"}}
+	if len(d.Changed) != 1 || d.Changed[0].URI != "https://example.com/d" ||
+		!reflect.DeepEqual(d.Changed[0].Changes, []FieldChange{want}) {
+		t.Errorf("Diff = %+v", d)
+	}
+
+	merged, conflicts := MergeThreeWay(&base, &ours, &theirs, ConflictReport)
+	if len(conflicts) != 0 {
+		t.Fatalf("conflicts: %v", conflicts)
+	}
+	links := func(path string) []string {
+		id, ok := merged.Lookup(mustParseURL("https://example.com/" + path))
+		if !ok {
+			t.Fatalf("%s missing from merge", path)
+		}
+		var got []string
+		for target, kind := range merged.Links(id) {
+			got = append(got, string(kind)+" "+linkPaths(&merged, []Id{target})[0])
+		}
+		return got
+	}
+	tests := map[string][]string{
+		"b": {"child-of a"},
+		"c": {"child-of b"},
+		"d": nil,
+		"e": {"related a"},
+	}
+	for path, want := range tests {
+		if got := links(path); !slices.Equal(got, want) {
+			t.Errorf("merged links from %s = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Normalizer maps a URL to the key a collection files it under. Entities whose
+// URLs have the same key are the same entity, and Upsert absorbs one into the
+// other.
+type Normalizer func(*url.URL) string
+
+// URLRule names a difference between two URLs that a Normalizer built by
+// NewNormalizer ignores.
+type URLRule string
+
+const (
+	// RuleHost ignores the case of the host.
+	RuleHost URLRule = "host"
+	// RuleScheme treats http and https as the same.
+	RuleScheme URLRule = "scheme"
+	// RuleWWW ignores a leading "www." in the host.
+	RuleWWW URLRule = "www"
+	// RuleSlash ignores a trailing slash in the path.
+	RuleSlash URLRule = "slash"
+	// RuleTracking ignores utm_* query parameters.
+	RuleTracking URLRule = "tracking"
+	// RuleFragment ignores the fragment.
+	RuleFragment URLRule = "fragment"
+)
+
+// URLRules lists every rule, in the order NewNormalizer applies them.
+var URLRules = []URLRule{RuleHost, RuleScheme, RuleWWW, RuleSlash, RuleTracking, RuleFragment}
+
+// ParseURLRules parses a comma-separated list of rule names. "all" stands for
+// every rule, and "none" or the empty string for no rule.
+func ParseURLRules(s string) ([]URLRule, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return nil, nil
+	case "all":
+		return slices.Clone(URLRules), nil
+	}
+
+	var rules []URLRule
+	for name := range strings.SplitSeq(s, ",") {
+		rule := URLRule(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(URLRules, rule) {
+			return nil, fmt.Errorf("invalid URL rule %q: expected host, scheme, www, slash, tracking, fragment, all, or none", name)
+		}
+		if !slices.Contains(rules, rule) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// NewNormalizer returns a Normalizer that ignores the differences named by
+// rules, applying them in URLRules order whatever order they are given in.
+// With no rules, it keys a URL on its string form, as a collection does by
+// default.
+func NewNormalizer(rules ...URLRule) Normalizer {
+	rules = slices.Clone(rules)
+	slices.SortStableFunc(rules, func(a, b URLRule) int {
+		return slices.Index(URLRules, a) - slices.Index(URLRules, b)
+	})
+	return func(uri *url.URL) string {
+		u := *uri
+		for _, rule := range rules {
+			switch rule {
+			case RuleHost:
+				u.Host = strings.ToLower(u.Host)
+			case RuleScheme:
+				if u.Scheme == "http" {
+					u.Scheme = "https"
+				}
+			case RuleWWW:
+				if host, ok := strings.CutPrefix(u.Host, "www."); ok && host != "" {
+					u.Host = host
+				}
+			case RuleSlash:
+				u.Path = strings.TrimRight(u.Path, "/")
+				u.RawPath = strings.TrimRight(u.RawPath, "/")
+			case RuleTracking:
+				dropTracking(&u)
+			case RuleFragment:
+				u.Fragment = ""
+				u.RawFragment = ""
+			}
+		}
+		return u.String()
+	}
+}
+
+// dropTracking removes utm_* parameters from the query of u. A query without
+// them is left as written.
+func dropTracking(u *url.URL) {
+	query := u.Query()
+	dropped := false
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			delete(query, key)
+			dropped = true
+		}
+	}
+	if dropped {
+		u.RawQuery = query.Encode()
+	}
+}
+
+// SetNormalizer files the entities of c under the keys normalize gives their
+// URLs, so that Upsert absorbs an entity into one whose URL differs from its
+// own only in ways normalize ignores. A nil normalize restores the default,
+// which keys each URL on its string form. Entities c already holds that share
+// a key are left apart, with later ones no longer found by URL; Dedupe
+// combines them.
+func (c *Collection) SetNormalizer(normalize Normalizer) {
+	c.normalize = normalize
+	c.urls = make(map[string]uint, len(c.entities))
+	for i, entity := range c.entities {
+		key := c.key(entity.URI)
+		if _, exists := c.urls[key]; !exists {
+			c.urls[key] = uint(i)
+		}
+	}
+}
+
+func (c *Collection) key(uri *url.URL) string {
+	if c.normalize == nil {
+		return uri.String()
+	}
+	return c.normalize(uri)
+}
+
+// Duplicates returns the clusters of entities in c whose URLs normalize gives
+// the same key, each cluster in insertion order, and the clusters in the
+// order of their first entities. Entities with a key of their own are not
+// listed.
+func (c *Collection) Duplicates(normalize Normalizer) [][]Id {
+	clusters := make(map[string][]Id)
+	var keys []string
+	for i, entity := range c.entities {
+		key := normalize(entity.URI)
+		if _, exists := clusters[key]; !exists {
+			keys = append(keys, key)
+		}
+		clusters[key] = append(clusters[key], c.idAt(uint(i)))
+	}
+
+	var duplicates [][]Id
+	for _, key := range keys {
+		if len(clusters[key]) > 1 {
+			duplicates = append(duplicates, clusters[key])
+		}
+	}
+	return duplicates
+}
+
+// Dedupe returns a collection in which each cluster Duplicates reports is
+// absorbed into its first entity, which keeps its URL. The result files URLs
+// under normalize, as SetNormalizer does. c is left as it was: the result
+// shares no maps or slices with it.
+func (c *Collection) Dedupe(normalize Normalizer) Collection {
+	// Merge shares the entities it inserts with their source, and absorbing
+	// the rest of a cluster would change them.
+	source := Collection{
+		entities: make([]Entity, len(c.entities)),
+		edges:    c.edges,
+		links:    c.links,
+	}
+	for i, entity := range c.entities {
+		source.entities[i] = entity.clone()
+	}
+
+	deduped := NewCollection()
+	deduped.SetNormalizer(normalize)
+	deduped.Merge(&source)
+	return deduped
+}
@@ -0,0 +1,143 @@
+package types
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizer(t *testing.T) {
+	all := NewNormalizer(URLRules...)
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://example.com/a", "http://example.com/a", true},
+		{"https://example.com/a", "https://www.example.com/a", true},
+		{"https://example.com/a", "https://example.com/a/", true},
+		{"https://example.com/", "https://example.com", true},
+		{"https://example.com/a", "https://EXAMPLE.com/a", true},
+		{"https://example.com/a", "https://example.com/a#intro", true},
+		{"https://example.com/a?id=1", "https://example.com/a?utm_source=rss&id=1", true},
+		{"https://example.com/a?id=1", "https://example.com/a?id=2", false},
+		{"https://example.com/a", "https://example.com/A", false},
+		{"https://example.com/a", "ftp://example.com/a", false},
+	}
+	for _, tt := range tests {
+		a, b := all(mustParseURL(tt.a)), all(mustParseURL(tt.b))
+		if (a == b) != tt.same {
+			t.Errorf("%s and %s normalized to %s and %s, want same = %v", tt.a, tt.b, a, b, tt.same)
+		}
+	}
+
+	reversed := NewNormalizer(RuleWWW, RuleHost)
+	if got := reversed(mustParseURL("https://WWW.Example.com/a")); got != "https://example.com/a" {
+		t.Errorf("normalizer with rules out of order gave %s, want https://example.com/a", got)
+	}
+
+	none := NewNormalizer()
+	if got := none(mustParseURL("http://www.example.com/a/")); got != "http://www.example.com/a/" {
+		t.Errorf("normalizer without rules changed URL to %s", got)
+	}
+}
+
+func TestParseURLRules(t *testing.T) {
+	rules, err := ParseURLRules("www, Slash,www")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0] != RuleWWW || rules[1] != RuleSlash {
+		t.Errorf("ParseURLRules = %v, want [www slash]", rules)
+	}
+
+	if rules, _ := ParseURLRules("all"); len(rules) != len(URLRules) {
+		t.Errorf("ParseURLRules(all) = %v", rules)
+	}
+	if rules, _ := ParseURLRules("none"); rules != nil {
+		t.Errorf("ParseURLRules(none) = %v", rules)
+	}
+	if _, err := ParseURLRules("www,query"); err == nil || !strings.Contains(err.Error(), `"query"`) {
+		t.Errorf("ParseURLRules(www,query) error = %v", err)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	entity := func(uri string, label Label) Entity {
+		return Entity{
+			URI:       mustParseURL(uri),
+			CreatedAt: CreatedAt(time.Unix(100, 0)),
+			UpdatedAt: []UpdatedAt{},
+			Names:     map[Name]struct{}{},
+			Labels:    map[Label]struct{}{label: {}},
+		}
+	}
+
+	coll := NewCollection()
+	a := coll.Upsert(entity("https://example.com/a", "first"))
+	b := coll.Upsert(entity("https://example.com/b", "other"))
+	a2 := coll.Upsert(entity("http://www.example.com/a/", "second"))
+	coll.AddEdges(a, b)
+	coll.AddEdges(a2, b)
+	coll.AddEdges(a, a2)
+
+	normalize := NewNormalizer(URLRules...)
+	clusters := coll.Duplicates(normalize)
+	if len(clusters) != 1 || len(clusters[0]) != 2 || clusters[0][0] != a || clusters[0][1] != a2 {
+		t.Fatalf("Duplicates = %v, want [[a a2]]", clusters)
+	}
+
+	before := slices.Collect(coll.Entities())
+	for i := range before {
+		before[i] = before[i].clone()
+	}
+
+	deduped := coll.Dedupe(normalize)
+	if got := slices.Collect(coll.Entities()); !slices.EqualFunc(got, before, Entity.Equal) {
+		t.Errorf("Dedupe changed its receiver:\ngot:  %+v\nwant: %+v", got, before)
+	}
+	if deduped.Len() != 2 {
+		t.Fatalf("Len after Dedupe = %d, want 2", deduped.Len())
+	}
+	kept := deduped.entities[0]
+	if kept.URI.String() != "https://example.com/a" {
+		t.Errorf("kept URI %s, want the first", kept.URI)
+	}
+	if _, ok := kept.Labels["second"]; !ok {
+		t.Errorf("labels of absorbed entity lost: %v", kept.Labels)
+	}
+	if got := deduped.edges[0]; len(got) != 1 || got[0] != 1 {
+		t.Errorf("edges of kept entity = %v, want [1] without a self-loop or duplicate", got)
+	}
+
+	// The deduped collection goes on absorbing variant URLs.
+	deduped.Upsert(entity("https://example.com/b/#top", "third"))
+	if deduped.Len() != 2 {
+		t.Errorf("Upsert of a variant URL added an entity")
+	}
+}
+
+func TestRemoveRefilesSharedKey(t *testing.T) {
+	coll := NewCollection()
+	a := coll.Upsert(makeEntity("https://example.com/a"))
+	coll.Upsert(makeEntity("https://example.com/b"))
+	a2 := coll.Upsert(makeEntity("https://www.example.com/a"))
+
+	// Both URLs of a are filed under one key, which names the first.
+	coll.SetNormalizer(NewNormalizer(RuleWWW))
+	coll.Remove(a)
+	if id, ok := coll.Lookup(mustParseURL("https://example.com/a")); !ok || id != a2 {
+		t.Errorf("Lookup after Remove = %v, %v, want the entity left under the key", id, ok)
+	}
+
+	// Update moving an entity off a shared key refiles it the same way.
+	coll.SetNormalizer(nil)
+	coll.Upsert(makeEntity("https://example.com/a"))
+	coll.SetNormalizer(NewNormalizer(RuleWWW))
+	if err := coll.Update(a2, func(e *Entity) { e.URI = mustParseURL("https://example.com/c") }); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if id, ok := coll.Lookup(mustParseURL("https://www.example.com/a")); !ok || coll.Get(id).URI.String() != "https://example.com/a" {
+		t.Errorf("Lookup after Update = %v, %v, want the entity left under the key", id, ok)
+	}
+}
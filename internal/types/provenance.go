@@ -0,0 +1,171 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+// Source identifies the record of an input that entity data was read from.
+type Source struct {
+	// File names the input, as given on the command line.
+	File string
+	// Format is the name of the input's format.
+	Format string
+	// Record is the 1-based position of the entity among those read from
+	// File, or 0 if unknown.
+	Record int
+}
+
+// Contribution records the values one source gave an entity, before they
+// were absorbed into it. The values are those the source read, before label
+// mappings and merging, so a label or name can be traced to every source
+// that gave it.
+type Contribution struct {
+	Source        Source
+	CreatedAt     CreatedAt
+	UpdatedAt     []UpdatedAt
+	Names         []Name
+	Labels        []Label
+	Extended      []Extended
+	LastVisitedAt LastVisitedAt
+}
+
+// RecordSource records e as read from src, unless e already carries its
+// provenance, as when it was read from a collection that recorded it.
+func (e *Entity) RecordSource(src Source) {
+	if len(e.Provenance) > 0 {
+		return
+	}
+
+	names := make([]Name, 0, len(e.Names))
+	for _, name := range MapToSortedSlice(e.Names) {
+		names = append(names, Name(name))
+	}
+	labels := make([]Label, 0, len(e.Labels))
+	for _, label := range MapToSortedSlice(e.Labels) {
+		labels = append(labels, Label(label))
+	}
+
+	e.Provenance = []Contribution{{
+		Source:        src,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     slices.Clone(e.UpdatedAt),
+		Names:         names,
+		Labels:        labels,
+		Extended:      slices.Clone(e.Extended),
+		LastVisitedAt: e.LastVisitedAt,
+	}}
+}
+
+// RecordSource records each entity of c as read from file in format, with
+// its position in c as its record.
+func (c *Collection) RecordSource(file, format string) {
+	for i := range c.entities {
+		c.entities[i].RecordSource(Source{File: file, Format: format, Record: i + 1})
+	}
+}
+
+// HasProvenance reports whether any entity of c carries its provenance.
+func (c *Collection) HasProvenance() bool {
+	return slices.ContainsFunc(c.entities, func(e Entity) bool { return len(e.Provenance) > 0 })
+}
+
+// LabelSources returns the sources that gave e label.
+func (e Entity) LabelSources(label Label) []Source {
+	var sources []Source
+	for _, c := range e.Provenance {
+		if slices.Contains(c.Labels, label) {
+			sources = append(sources, c.Source)
+		}
+	}
+	return sources
+}
+
+// mergeProvenance returns the contributions of p followed by those of q
+// from sources p does not already list.
+func mergeProvenance(p, q []Contribution) []Contribution {
+	merged := slices.Clone(p)
+	for _, c := range q {
+		if !slices.ContainsFunc(merged, func(d Contribution) bool { return d.Source == c.Source }) {
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+type contributionRepr struct {
+	File          string   `yaml:"file"                    json:"file"`
+	Format        string   `yaml:"format,omitempty"        json:"format,omitempty"`
+	Record        int      `yaml:"record,omitempty"        json:"record,omitempty"`
+	CreatedAt     int64    `yaml:"createdAt"               json:"createdAt"`
+	UpdatedAt     []int64  `yaml:"updatedAt,omitempty"     json:"updatedAt,omitempty"`
+	Names         []string `yaml:"names,omitempty"         json:"names,omitempty"`
+	Labels        []string `yaml:"labels,omitempty"        json:"labels,omitempty"`
+	Extended      []string `yaml:"extended,omitempty"      json:"extended,omitempty"`
+	LastVisitedAt *int64   `yaml:"lastVisitedAt,omitempty" json:"lastVisitedAt,omitempty"`
+}
+
+func provenanceToRepr(provenance []Contribution) []contributionRepr {
+	if len(provenance) == 0 {
+		return nil
+	}
+
+	reprs := make([]contributionRepr, len(provenance))
+	for i, c := range provenance {
+		r := contributionRepr{
+			File:      c.Source.File,
+			Format:    c.Source.Format,
+			Record:    c.Source.Record,
+			CreatedAt: c.CreatedAt.Unix(),
+		}
+		for _, u := range c.UpdatedAt {
+			r.UpdatedAt = append(r.UpdatedAt, u.Unix())
+		}
+		for _, name := range c.Names {
+			r.Names = append(r.Names, string(name))
+		}
+		for _, label := range c.Labels {
+			r.Labels = append(r.Labels, string(label))
+		}
+		for _, ext := range c.Extended {
+			r.Extended = append(r.Extended, string(ext))
+		}
+		if t, ok := c.LastVisitedAt.Get(); ok {
+			unix := t.Unix()
+			r.LastVisitedAt = &unix
+		}
+		reprs[i] = r
+	}
+	return reprs
+}
+
+func provenanceFromRepr(reprs []contributionRepr) []Contribution {
+	if len(reprs) == 0 {
+		return nil
+	}
+
+	provenance := make([]Contribution, len(reprs))
+	for i, r := range reprs {
+		c := Contribution{
+			Source:    Source{File: r.File, Format: r.Format, Record: r.Record},
+			CreatedAt: CreatedAt(time.Unix(r.CreatedAt, 0)),
+		}
+		for _, unix := range r.UpdatedAt {
+			c.UpdatedAt = append(c.UpdatedAt, UpdatedAt(time.Unix(unix, 0)))
+		}
+		for _, name := range r.Names {
+			c.Names = append(c.Names, Name(name))
+		}
+		for _, label := range r.Labels {
+			c.Labels = append(c.Labels, Label(label))
+		}
+		for _, ext := range r.Extended {
+			c.Extended = append(c.Extended, Extended(ext))
+		}
+		if r.LastVisitedAt != nil {
+			c.LastVisitedAt = NewLastVisitedAt(time.Unix(*r.LastVisitedAt, 0))
+		}
+		provenance[i] = c
+	}
+	return provenance
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func provenanceTestEntity(uri string, labels ...string) Entity {
+	e := Entity{
+		URI:       mustParseURL(uri),
+		CreatedAt: CreatedAt(time.Unix(100, 0)),
+		UpdatedAt: []UpdatedAt{},
+		Names:     map[Name]struct{}{},
+		Labels:    map[Label]struct{}{},
+	}
+	for _, label := range labels {
+		e.Labels[Label(label)] = struct{}{}
+	}
+	return e
+}
+
+func TestProvenanceThroughUpsert(t *testing.T) {
+	first := NewCollection()
+	first.Upsert(provenanceTestEntity("https://example.com/a", "go"))
+	first.Upsert(provenanceTestEntity("https://example.com/b"))
+	first.RecordSource("first.json", "pinboard_json")
+
+	second := NewCollection()
+	second.Upsert(provenanceTestEntity("https://example.com/b"))
+	second.Upsert(provenanceTestEntity("https://example.com/a", "go", "bad"))
+	second.RecordSource("second.html", "html")
+
+	first.Merge(&second)
+
+	id, ok := first.Lookup(mustParseURL("https://example.com/a"))
+	if !ok {
+		t.Fatal("entity a missing after merge")
+	}
+	e := first.Get(id)
+
+	if len(e.Provenance) != 2 {
+		t.Fatalf("Provenance has %d contributions, want 2", len(e.Provenance))
+	}
+	want := []Source{{File: "first.json", Format: "pinboard_json", Record: 1}, {File: "second.html", Format: "html", Record: 2}}
+	if got := e.LabelSources("go"); !reflect.DeepEqual(got, want) {
+		t.Errorf("LabelSources(go) = %v, want %v", got, want)
+	}
+	if got := e.LabelSources("bad"); !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("LabelSources(bad) = %v, want %v", got, want[1:])
+	}
+
+	// Recording again keeps what was recorded when first read.
+	first.RecordSource("merged.yaml", "yaml")
+	if got := first.Get(id).Provenance; len(got) != 2 {
+		t.Errorf("RecordSource replaced existing provenance: %v", got)
+	}
+}
+
+func TestProvenanceRepr(t *testing.T) {
+	coll := NewCollection()
+	coll.Upsert(provenanceTestEntity("https://example.com/a", "go"))
+
+	plain, err := json.Marshal(&coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(plain), `"version":"0.1.0"`) || strings.Contains(string(plain), "provenance") {
+		t.Errorf("collection without provenance written as:\n%s", plain)
+	}
+
+	coll.RecordSource("first.json", "pinboard_json")
+	data, err := json.Marshal(&coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":"0.2.0"`) {
+		t.Errorf("collection with provenance not written as 0.2.0:\n%s", data)
+	}
+
+	var got Collection
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !got.HasProvenance() {
+		t.Fatal("provenance lost in round trip")
+	}
+	sources := got.entities[0].LabelSources("go")
+	if want := []Source{{File: "first.json", Format: "pinboard_json", Record: 1}}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("LabelSources(go) after round trip = %v, want %v", sources, want)
+	}
+}
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// Node is one node of a serialized collection: an entity, its position in
+// the collection, the positions of its neighbors, and its links. Streaming
+// parsers and formatters exchange nodes one at a time, so a collection can
+// pass through them without being held in memory.
+type Node struct {
+	ID     uint
+	Entity Entity
+	Edges  []uint
+	Links  []NodeLink
+}
+
+// NodeLink is a link from a node to the node at position To.
+type NodeLink struct {
+	To   uint
+	Kind LinkKind
+}
+
+func (n Node) MarshalJSON() ([]byte, error) {
+	edges := n.Edges
+	if edges == nil {
+		edges = []uint{}
+	}
+	return json.Marshal(nodeRepr{
+		ID:     n.ID,
+		Entity: n.Entity.toRepr(),
+		Edges:  edges,
+		Links:  nodeLinksToRepr(n.Links),
+	})
+}
+
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var aux nodeRepr
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var entity Entity
+	if err := entity.fromRepr(aux.Entity); err != nil {
+		return fmt.Errorf("node %d: %w", aux.ID, err)
+	}
+
+	var links []NodeLink
+	for _, r := range aux.Links {
+		if _, err := ParseLinkKind(string(r.Kind)); err != nil {
+			return fmt.Errorf("node %d: %w", aux.ID, err)
+		}
+		links = append(links, NodeLink{To: r.To, Kind: r.Kind})
+	}
+
+	n.ID = aux.ID
+	n.Entity = entity
+	n.Edges = aux.Edges
+	n.Links = links
+	return nil
+}
+
+func nodeLinksToRepr(links []NodeLink) []linkRepr {
+	if len(links) == 0 {
+		return nil
+	}
+	reprs := make([]linkRepr, len(links))
+	for i, l := range links {
+		reprs[i] = linkRepr{To: l.To, Kind: l.Kind}
+	}
+	return reprs
+}
+
+// Stream returns an iterator over the collection's nodes in insertion order.
+// It never yields an error; the error is there so a collection can be passed
+// wherever a StreamParser's nodes are expected.
+func (c *Collection) Stream() iter.Seq2[Node, error] {
+	return func(yield func(Node, error) bool) {
+		for i, entity := range c.entities {
+			node := Node{ID: uint(i), Entity: entity, Edges: c.edges[i], Links: c.nodeLinks(i)}
+			if !yield(node, nil) {
+				return
+			}
+		}
+	}
+}
+
+// nodeLinks returns the links from the entity at index as node links.
+func (c *Collection) nodeLinks(index int) []NodeLink {
+	if len(c.links[index]) == 0 {
+		return nil
+	}
+	links := make([]NodeLink, len(c.links[index]))
+	for i, l := range c.links[index] {
+		links[i] = NodeLink{To: l.to, Kind: l.kind}
+	}
+	return links
+}
+
+// Collect builds a collection from a stream of nodes, stopping at the first
+// error. Entities are upserted in stream order, so nodes sharing a URL are
+// absorbed into one. Edges keep the order and multiplicity they have in the
+// stream, and edges and links to IDs that never appear, as when a stream has
+// been filtered, are dropped.
+func Collect(nodes iter.Seq2[Node, error]) (Collection, error) {
+	coll := NewCollection()
+
+	type pending struct {
+		index uint
+		edges []uint
+		links []NodeLink
+	}
+
+	indices := make(map[uint]uint)
+	var read []pending
+
+	for node, err := range nodes {
+		if err != nil {
+			return Collection{}, err
+		}
+		if _, exists := indices[node.ID]; exists {
+			return Collection{}, fmt.Errorf("duplicate node id %d", node.ID)
+		}
+		id := coll.Upsert(node.Entity)
+		index := coll.indexOf(id)
+		indices[node.ID] = index
+		read = append(read, pending{index: index, edges: node.Edges, links: node.Links})
+	}
+
+	for _, p := range read {
+		for _, edge := range p.edges {
+			if to, exists := indices[edge]; exists {
+				coll.edges[p.index] = append(coll.edges[p.index], to)
+			}
+		}
+		for _, l := range p.links {
+			if to, exists := indices[l.To]; exists {
+				coll.addLink(p.index, link{to: to, kind: l.Kind})
+			}
+		}
+	}
+
+	return coll, nil
+}
@@ -0,0 +1,401 @@
+package types
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConflictPolicy chooses how MergeThreeWay resolves a change made on both
+// sides that cannot be combined.
+type ConflictPolicy string
+
+const (
+	// ConflictReport resolves nothing: a conflicting field keeps its base
+	// value, and an entity deleted on one side but changed on the other is
+	// kept as changed, so no edit is lost until the conflict is settled.
+	ConflictReport ConflictPolicy = "report"
+	// ConflictOurs resolves each conflict in favor of ours.
+	ConflictOurs ConflictPolicy = "ours"
+	// ConflictTheirs resolves each conflict in favor of theirs.
+	ConflictTheirs ConflictPolicy = "theirs"
+)
+
+// ParseConflictPolicy parses the name of a policy, where the empty string
+// means ConflictReport.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", string(ConflictReport):
+		return ConflictReport, nil
+	case string(ConflictOurs):
+		return ConflictOurs, nil
+	case string(ConflictTheirs):
+		return ConflictTheirs, nil
+	default:
+		return ConflictReport, fmt.Errorf("invalid conflict policy %q: expected report, ours, or theirs", s)
+	}
+}
+
+// Conflict is a change made on both sides of a three-way merge that could not
+// be combined. Field is named as in the serialized collection, or is "entity"
+// when one side deleted an entity the other changed. Values are rendered as
+// in FieldChange and joined with ", ", with "unset" for an unset value and
+// "deleted" or "changed" for an entity conflict.
+type Conflict struct {
+	URI    string `yaml:"uri"    json:"uri"`
+	Field  string `yaml:"field"  json:"field"`
+	Base   string `yaml:"base"   json:"base"`
+	Ours   string `yaml:"ours"   json:"ours"`
+	Theirs string `yaml:"theirs" json:"theirs"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s %s: base %s, ours %s, theirs %s", c.URI, c.Field, c.Base, c.Ours, c.Theirs)
+}
+
+// MergeThreeWay merges ours and theirs, two collections descended from base,
+// matching entities by URL. A change made on one side is kept, so that
+// deletions propagate and a label removed on one side stays removed. Names,
+// labels, Extended, UpdatedAt, edges, and links merge as sets: a value stays if
+// neither side removed it, and a value either side added is kept. A
+// single-valued field changed on both sides to different values conflicts
+// and is resolved by policy, except LastVisitedAt, which takes the later
+// visit, as does an entity deleted on one side and changed on the other,
+// if only in its edges or links. Entities added on both sides are absorbed
+// as by Upsert.
+//
+// The result lists the entities of ours first, in order, followed by those
+// only theirs has. Every conflict is returned whatever the policy, in the
+// order the entities are merged. Entities taken whole from one side share
+// their interior maps and slices with it.
+func MergeThreeWay(base, ours, theirs *Collection, policy ConflictPolicy) (Collection, []Conflict) {
+	merged := NewCollection()
+	var conflicts []Conflict
+
+	deleted := func(uri string, oursState, theirsState string) {
+		conflicts = append(conflicts, Conflict{
+			URI: uri, Field: "entity", Base: "present", Ours: oursState, Theirs: theirsState,
+		})
+	}
+
+	for _, o := range ours.entities {
+		b, inBase := base.lookupEntity(o)
+		t, inTheirs := theirs.lookupEntity(o)
+		switch {
+		case inBase && inTheirs:
+			entity, entityConflicts := mergeEntities(b, o, t, policy)
+			conflicts = append(conflicts, entityConflicts...)
+			merged.insert(entity)
+		case inBase:
+			if unchangedEntity(base, ours, b, o) {
+				continue
+			}
+			deleted(o.URI.String(), "changed", "deleted")
+			if policy != ConflictTheirs {
+				merged.insert(o)
+			}
+		case inTheirs:
+			entity := o.clone()
+			entity.absorb(t)
+			merged.insert(entity)
+		default:
+			merged.insert(o)
+		}
+	}
+
+	for _, t := range theirs.entities {
+		if _, inOurs := ours.findIndex(t.URI); inOurs {
+			continue
+		}
+		b, inBase := base.lookupEntity(t)
+		switch {
+		case !inBase:
+			merged.insert(t)
+		case !unchangedEntity(base, theirs, b, t):
+			deleted(t.URI.String(), "deleted", "changed")
+			if policy != ConflictOurs {
+				merged.insert(t)
+			}
+		}
+	}
+
+	mergedEdges := merge3Set(base.edgeSet(), ours.edgeSet(), theirs.edgeSet())
+	for _, side := range []*Collection{ours, theirs} {
+		for pair := range side.edgePairs() {
+			if _, ok := mergedEdges[pair]; !ok {
+				continue
+			}
+			from, fromOK := merged.urls[pair[0]]
+			to, toOK := merged.urls[pair[1]]
+			if fromOK && toOK {
+				merged.AddEdges(merged.idAt(from), merged.idAt(to))
+			}
+			delete(mergedEdges, pair)
+		}
+	}
+
+	mergedLinks := merge3Set(base.linkSet(), ours.linkSet(), theirs.linkSet())
+	for _, side := range []*Collection{ours, theirs} {
+		for triple := range side.linkTriples() {
+			if _, ok := mergedLinks[triple]; !ok {
+				continue
+			}
+			from, fromOK := merged.urls[triple.from]
+			to, toOK := merged.urls[triple.to]
+			if fromOK && toOK {
+				merged.Link(merged.idAt(from), merged.idAt(to), triple.kind)
+			}
+			delete(mergedLinks, triple)
+		}
+	}
+
+	return merged, conflicts
+}
+
+// unchangedEntity reports whether e, an entity of side, is as b is in base:
+// equal, and with the same edges and links to and from it. An entity whose
+// edges or links alone changed is changed, so that deleting it on the other
+// side conflicts.
+func unchangedEntity(base, side *Collection, b, e Entity) bool {
+	return e.Equal(b) && maps.Equal(base.relations(b), side.relations(e))
+}
+
+// relations returns the edges and links of the entity of c with the URL of
+// e, each named by its kind and the URL of the other end.
+func (c *Collection) relations(e Entity) map[string]struct{} {
+	set := make(map[string]struct{})
+	index, ok := c.findIndex(e.URI)
+	if !ok {
+		return set
+	}
+	for _, to := range c.edges[index] {
+		set["edge "+c.entities[to].URI.String()] = struct{}{}
+	}
+	for from, links := range c.links {
+		for _, l := range links {
+			switch {
+			case uint(from) == index:
+				set["link to "+string(l.kind)+" "+c.entities[l.to].URI.String()] = struct{}{}
+			case l.to == index:
+				set["link from "+string(l.kind)+" "+c.entities[from].URI.String()] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+// lookupEntity returns the entity of c with the URL of e.
+func (c *Collection) lookupEntity(e Entity) (Entity, bool) {
+	index, ok := c.findIndex(e.URI)
+	if !ok {
+		return Entity{}, false
+	}
+	return c.entities[index], true
+}
+
+// edgePair identifies an edge by the URLs of its ends, in sorted order.
+type edgePair [2]string
+
+// edgePairs returns an iterator over the edges of c in the order they were
+// added, each yielded once from each end.
+func (c *Collection) edgePairs() iter.Seq[edgePair] {
+	return func(yield func(edgePair) bool) {
+		for from, edges := range c.edges {
+			for _, to := range edges {
+				pair := edgePair{c.entities[from].URI.String(), c.entities[to].URI.String()}
+				if pair[1] < pair[0] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				if !yield(pair) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Collection) edgeSet() map[edgePair]struct{} {
+	set := make(map[edgePair]struct{})
+	for pair := range c.edgePairs() {
+		set[pair] = struct{}{}
+	}
+	return set
+}
+
+// linkTriple identifies a link by the URLs of its ends and its kind.
+type linkTriple struct {
+	from, to string
+	kind     LinkKind
+}
+
+// linkTriples returns an iterator over the links of c in insertion order of
+// their sources.
+func (c *Collection) linkTriples() iter.Seq[linkTriple] {
+	return func(yield func(linkTriple) bool) {
+		for from, links := range c.links {
+			for _, l := range links {
+				triple := linkTriple{c.entities[from].URI.String(), c.entities[l.to].URI.String(), l.kind}
+				if !yield(triple) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Collection) linkSet() map[linkTriple]struct{} {
+	set := make(map[linkTriple]struct{})
+	for triple := range c.linkTriples() {
+		set[triple] = struct{}{}
+	}
+	return set
+}
+
+// clone returns a copy of e that shares no maps or slices with it.
+func (e Entity) clone() Entity {
+	e.UpdatedAt = slices.Clone(e.UpdatedAt)
+	e.Names = maps.Clone(e.Names)
+	e.Labels = maps.Clone(e.Labels)
+	e.Extended = slices.Clone(e.Extended)
+	e.Folders = slices.Clone(e.Folders)
+	e.Provenance = slices.Clone(e.Provenance)
+	return e
+}
+
+func mergeEntities(b, o, t Entity, policy ConflictPolicy) (Entity, []Conflict) {
+	var conflicts []Conflict
+	conflict := func(field string, base, ours, theirs []string) {
+		conflicts = append(conflicts, Conflict{
+			URI:    o.URI.String(),
+			Field:  field,
+			Base:   conflictValue(base),
+			Ours:   conflictValue(ours),
+			Theirs: conflictValue(theirs),
+		})
+	}
+
+	merged := Entity{URI: o.URI}
+
+	sameTime := func(x, y CreatedAt) bool { return time.Time(x).Equal(time.Time(y)) }
+	var ok bool
+	if merged.CreatedAt, ok = merge3(b.CreatedAt, o.CreatedAt, t.CreatedAt, sameTime, policy); !ok {
+		conflict("createdAt",
+			[]string{formatDiffTime(time.Time(b.CreatedAt))},
+			[]string{formatDiffTime(time.Time(o.CreatedAt))},
+			[]string{formatDiffTime(time.Time(t.CreatedAt))})
+	}
+
+	sameUpdate := func(x, y UpdatedAt) bool { return time.Time(x).Equal(time.Time(y)) }
+	merged.UpdatedAt = merge3List(b.UpdatedAt, o.UpdatedAt, t.UpdatedAt, sameUpdate)
+	sort.Slice(merged.UpdatedAt, func(i, j int) bool {
+		return merged.UpdatedAt[i].Before(merged.UpdatedAt[j])
+	})
+
+	merged.Names = merge3Set(b.Names, o.Names, t.Names)
+	merged.Labels = merge3Set(b.Labels, o.Labels, t.Labels)
+
+	if merged.Shared, ok = merge3(b.Shared, o.Shared, t.Shared, sameValue, policy); !ok {
+		conflict("shared", diffFlag(b.Shared.optBool), diffFlag(o.Shared.optBool), diffFlag(t.Shared.optBool))
+	}
+	if merged.ToRead, ok = merge3(b.ToRead, o.ToRead, t.ToRead, sameValue, policy); !ok {
+		conflict("toRead", diffFlag(b.ToRead.optBool), diffFlag(o.ToRead.optBool), diffFlag(t.ToRead.optBool))
+	}
+	if merged.IsFeed, ok = merge3(b.IsFeed, o.IsFeed, t.IsFeed, sameValue, policy); !ok {
+		conflict("isFeed", diffFlag(b.IsFeed.optBool), diffFlag(o.IsFeed.optBool), diffFlag(t.IsFeed.optBool))
+	}
+
+	merged.Extended = merge3List(b.Extended, o.Extended, t.Extended, sameValue)
+
+	// Two different visits are not a conflict: the later one happened last.
+	if merged.LastVisitedAt, ok = merge3(b.LastVisitedAt, o.LastVisitedAt, t.LastVisitedAt, LastVisitedAt.Equal, policy); !ok {
+		merged.LastVisitedAt = o.LastVisitedAt.Merge(t.LastVisitedAt)
+	}
+
+	merged.Provenance = mergeProvenance(o.Provenance, t.Provenance)
+
+	merged.Folders = slices.Clone(o.Folders)
+	for _, path := range t.Folders {
+		if !slices.ContainsFunc(merged.Folders, path.Equal) {
+			merged.Folders = append(merged.Folders, path)
+		}
+	}
+
+	return merged, conflicts
+}
+
+func sameValue[T comparable](x, y T) bool {
+	return x == y
+}
+
+// merge3 merges a single-valued field: a side that kept the base value yields
+// to the other. When the sides changed it to different values, merge3
+// resolves the conflict by policy and reports false.
+func merge3[T any](base, ours, theirs T, equal func(T, T) bool, policy ConflictPolicy) (T, bool) {
+	switch {
+	case equal(ours, theirs), equal(base, theirs):
+		return ours, true
+	case equal(base, ours):
+		return theirs, true
+	}
+
+	switch policy {
+	case ConflictOurs:
+		return ours, false
+	case ConflictTheirs:
+		return theirs, false
+	default:
+		return base, false
+	}
+}
+
+// merge3Set keeps a member of base only if both sides kept it, and each
+// member a side added.
+func merge3Set[K comparable](base, ours, theirs map[K]struct{}) map[K]struct{} {
+	merged := make(map[K]struct{})
+	for k := range ours {
+		_, inBase := base[k]
+		_, inTheirs := theirs[k]
+		if !inBase || inTheirs {
+			merged[k] = struct{}{}
+		}
+	}
+	for k := range theirs {
+		if _, inBase := base[k]; !inBase {
+			merged[k] = struct{}{}
+		}
+	}
+	return merged
+}
+
+// merge3List is merge3Set for lists: the values of ours that theirs kept or
+// that are new, in the order of ours, are followed by those theirs added that
+// ours lacks.
+func merge3List[T any](base, ours, theirs []T, equal func(T, T) bool) []T {
+	contains := func(s []T, v T) bool {
+		return slices.ContainsFunc(s, func(w T) bool { return equal(v, w) })
+	}
+
+	var merged []T
+	for _, v := range ours {
+		if !contains(base, v) || contains(theirs, v) {
+			merged = append(merged, v)
+		}
+	}
+	for _, v := range theirs {
+		if !contains(base, v) && !contains(ours, v) {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+func conflictValue(values []string) string {
+	if len(values) == 0 {
+		return "unset"
+	}
+	return strings.Join(values, ", ")
+}
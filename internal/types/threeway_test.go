@@ -0,0 +1,154 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeThreeWay(t *testing.T) {
+	base := NewCollection()
+	a := makeEntity("https://example.com/a")
+	a.Labels["keep"] = struct{}{}
+	a.Labels["drop"] = struct{}{}
+	a.Shared = NewShared(true)
+	aID := base.Upsert(a)
+	bID := base.Upsert(makeEntity("https://example.com/b"))
+	base.AddEdges(aID, bID)
+	base.Upsert(makeEntity("https://example.com/gone"))
+
+	// Ours removes a label and unshares a; theirs adds a label, deletes gone,
+	// and adds c joined to a.
+	ours := NewCollection()
+	a1 := makeEntity("https://example.com/a")
+	a1.Labels["keep"] = struct{}{}
+	a1.Shared = NewShared(false)
+	ours.Upsert(a1)
+	ours.Upsert(makeEntity("https://example.com/b"))
+	ours.Upsert(makeEntity("https://example.com/gone"))
+
+	theirs := NewCollection()
+	a2 := makeEntity("https://example.com/a")
+	a2.Labels["keep"] = struct{}{}
+	a2.Labels["drop"] = struct{}{}
+	a2.Labels["new"] = struct{}{}
+	a2.Shared = NewShared(true)
+	a2ID := theirs.Upsert(a2)
+	b2ID := theirs.Upsert(makeEntity("https://example.com/b"))
+	cID := theirs.Upsert(makeEntity("https://example.com/c"))
+	theirs.AddEdges(a2ID, b2ID)
+	theirs.AddEdges(a2ID, cID)
+
+	merged, conflicts := MergeThreeWay(&base, &ours, &theirs, ConflictReport)
+	if len(conflicts) != 0 {
+		t.Errorf("conflicts = %v, want none", conflicts)
+	}
+
+	var uris []string
+	for e := range merged.Entities() {
+		uris = append(uris, e.URI.Path)
+	}
+	if !slices.Equal(uris, []string{"/a", "/b", "/c"}) {
+		t.Fatalf("entities = %v, want [/a /b /c]", uris)
+	}
+
+	merged0 := merged.entities[0]
+	if labels := MapToSortedSlice(merged0.Labels); !slices.Equal(labels, []string{"keep", "new"}) {
+		t.Errorf("Labels = %v, want [keep new]", labels)
+	}
+	if shared, _ := merged0.Shared.Get(); shared {
+		t.Error("Shared = true, want ours' change to false")
+	}
+
+	// Ours dropped the edge from a to b, and theirs added one from a to c.
+	if neighbors := merged.neighborURIs(0); !slices.Equal(neighbors, []string{"https://example.com/c"}) {
+		t.Errorf("neighbors of a = %v, want [c]", neighbors)
+	}
+}
+
+func TestMergeThreeWayConflicts(t *testing.T) {
+	base := NewCollection()
+	a := makeEntity("https://example.com/a")
+	a.ToRead = NewToRead(true)
+	base.Upsert(a)
+	base.Upsert(makeEntity("https://example.com/b"))
+
+	ours := NewCollection()
+	a1 := makeEntity("https://example.com/a")
+	a1.ToRead = NewToRead(false)
+	ours.Upsert(a1)
+	b1 := makeEntity("https://example.com/b")
+	b1.Names["B"] = struct{}{}
+	ours.Upsert(b1)
+
+	theirs := NewCollection()
+	a2 := makeEntity("https://example.com/a")
+	theirs.Upsert(a2)
+
+	tests := []struct {
+		policy     ConflictPolicy
+		wantToRead []string
+		wantB      bool
+	}{
+		{ConflictReport, []string{"true"}, true},
+		{ConflictOurs, []string{"false"}, true},
+		{ConflictTheirs, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			merged, conflicts := MergeThreeWay(&base, &ours, &theirs, tt.policy)
+
+			want := []Conflict{
+				{URI: "https://example.com/a", Field: "toRead", Base: "true", Ours: "false", Theirs: "unset"},
+				{URI: "https://example.com/b", Field: "entity", Base: "present", Ours: "changed", Theirs: "deleted"},
+			}
+			if !slices.Equal(conflicts, want) {
+				t.Errorf("conflicts = %v, want %v", conflicts, want)
+			}
+
+			if got := diffFlag(merged.entities[0].ToRead.optBool); !slices.Equal(got, tt.wantToRead) {
+				t.Errorf("ToRead = %v, want %v", got, tt.wantToRead)
+			}
+			if _, ok := merged.Lookup(mustParseURL("https://example.com/b")); ok != tt.wantB {
+				t.Errorf("b present = %v, want %v", ok, tt.wantB)
+			}
+		})
+	}
+}
+
+func TestMergeThreeWayRelationsConflict(t *testing.T) {
+	base := NewCollection()
+	for _, uri := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/x"} {
+		base.Upsert(makeEntity(uri))
+	}
+
+	// Ours changed only the edges of b and deleted x; theirs deleted b and
+	// changed only the links of x.
+	ours := NewCollection()
+	oa := ours.Upsert(makeEntity("https://example.com/a"))
+	ob := ours.Upsert(makeEntity("https://example.com/b"))
+	ours.AddEdges(oa, ob)
+
+	theirs := NewCollection()
+	ta := theirs.Upsert(makeEntity("https://example.com/a"))
+	tx := theirs.Upsert(makeEntity("https://example.com/x"))
+	theirs.Link(tx, ta, LinkChildOf)
+
+	_, conflicts := MergeThreeWay(&base, &ours, &theirs, ConflictReport)
+	want := []Conflict{
+		{URI: "https://example.com/b", Field: "entity", Base: "present", Ours: "changed", Theirs: "deleted"},
+		{URI: "https://example.com/x", Field: "entity", Base: "present", Ours: "deleted", Theirs: "changed"},
+	}
+	if !slices.Equal(conflicts, want) {
+		t.Errorf("conflicts = %v, want %v", conflicts, want)
+	}
+
+	// An entity left as it was is still deleted without a conflict.
+	merged, conflicts := MergeThreeWay(&base, &base, &theirs, ConflictReport)
+	if len(conflicts) != 0 {
+		t.Errorf("conflicts = %v, want none", conflicts)
+	}
+	if _, ok := merged.Lookup(mustParseURL("https://example.com/b")); ok {
+		t.Error("b, deleted by theirs and unchanged in ours, was kept")
+	}
+}
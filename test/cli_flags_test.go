@@ -24,6 +24,11 @@ func writeFlagsTestInput(t *testing.T) string {
 
 func runHbt(t *testing.T, args ...string) (stdout, stderr string, exitCode int) {
 	t.Helper()
+	return runHbtWithStdin(t, "", args...)
+}
+
+func runHbtWithStdin(t *testing.T, stdin string, args ...string) (stdout, stderr string, exitCode int) {
+	t.Helper()
 
 	binaryPath := hbtBinaryPath(t)
 	if _, err := os.Stat(binaryPath); err != nil {
@@ -32,6 +37,7 @@ func runHbt(t *testing.T, args ...string) (stdout, stderr string, exitCode int)
 
 	var out, errOut strings.Builder
 	cmd := exec.Command(binaryPath, args...)
+	cmd.Stdin = strings.NewReader(stdin)
 	cmd.Stdout = &out
 	cmd.Stderr = &errOut
 
@@ -158,6 +164,150 @@ func TestCLIErrors(t *testing.T) {
 	}
 }
 
+func TestCLIStdin(t *testing.T) {
+	input := writeFlagsTestInput(t)
+	fromFile, _, _ := runHbt(t, "-t", "yaml", input)
+
+	stdout, stderr, exitCode := runHbtWithStdin(t, flagsTestInput, "-t", "yaml", "-")
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if stdout != fromFile {
+		t.Errorf("stdin output differs from file output:\nstdin:\n%s\nfile:\n%s", stdout, fromFile)
+	}
+}
+
+func TestCLIStdinUnknownContent(t *testing.T) {
+	_, stderr, exitCode := runHbtWithStdin(t, "just some notes\n", "-t", "yaml", "-")
+	if exitCode == 0 {
+		t.Fatal("expected non-zero exit")
+	}
+	if !strings.Contains(stderr, "cannot detect input format of stdin") {
+		t.Errorf("unexpected stderr: %s", stderr)
+	}
+}
+
+func TestCLICompressedRoundTrip(t *testing.T) {
+	input := writeFlagsTestInput(t)
+	plain, _, _ := runHbt(t, "-t", "yaml", input)
+
+	for _, suffix := range []string{".gz", ".zst", ".xz"} {
+		t.Run(suffix, func(t *testing.T) {
+			outFile := filepath.Join(t.TempDir(), "out.yaml"+suffix)
+
+			_, stderr, exitCode := runHbt(t, "-o", outFile, input)
+			if exitCode != 0 {
+				t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+			}
+
+			written, err := os.ReadFile(outFile)
+			if err != nil {
+				t.Fatalf("output file not written: %v", err)
+			}
+			if string(written) == plain {
+				t.Fatal("output file was not compressed")
+			}
+
+			// Read back through stdin, where only the magic bytes tell
+			// that the input is compressed.
+			stdout, stderr, exitCode := runHbtWithStdin(t, string(written), "-t", "yaml", "-")
+			if exitCode != 0 {
+				t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+			}
+			if stdout != plain {
+				t.Errorf("compressed round trip changed the collection:\nbefore:\n%s\nafter:\n%s", plain, stdout)
+			}
+		})
+	}
+}
+
+func TestCLIMultipleInputs(t *testing.T) {
+	input := writeFlagsTestInput(t)
+
+	dir := t.TempDir()
+	markdown := filepath.Join(dir, "notes.txt")
+	const notes = "# January 3, 2021\n\n- [B again](https://example.com/b)\n- [C](https://example.com/c)\n"
+	if err := os.WriteFile(markdown, []byte(notes), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, exitCode := runHbt(t, "--info", input, markdown+":markdown")
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if stdout != "Collection contains 3 entities\n" {
+		t.Errorf("unexpected --info output: %q", stdout)
+	}
+
+	wantSummary := input + " (json): 2 new, 0 absorbed\n" +
+		markdown + " (markdown): 1 new, 1 absorbed\n"
+	if stderr != wantSummary {
+		t.Errorf("summary:\ngot:\n%s\nwant:\n%s", stderr, wantSummary)
+	}
+}
+
+func TestCLIMultipleInputsDuplicateInFirst(t *testing.T) {
+	input := writeFlagsTestInput(t)
+
+	markdown := filepath.Join(t.TempDir(), "notes.md")
+	const notes = "# January 3, 2021\n\n- [A](https://example.com/a)\n- [C](https://example.com/c)\n- [A again](https://example.com/a)\n"
+	if err := os.WriteFile(markdown, []byte(notes), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, exitCode := runHbt(t, "--info", markdown, input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if stdout != "Collection contains 3 entities\n" {
+		t.Errorf("unexpected --info output: %q", stdout)
+	}
+
+	wantSummary := markdown + " (markdown): 2 new, 1 absorbed\n" +
+		input + " (json): 1 new, 1 absorbed\n"
+	if stderr != wantSummary {
+		t.Errorf("summary:\ngot:\n%s\nwant:\n%s", stderr, wantSummary)
+	}
+}
+
+func TestCLIMultipleInputsErrors(t *testing.T) {
+	input := writeFlagsTestInput(t)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+	}{
+		{
+			name:       "stdin twice",
+			args:       []string{"--info", "-", "-:json"},
+			wantStderr: "only once",
+		},
+		{
+			name:       "output-only format",
+			args:       []string{"--info", input + ":atom"},
+			wantStderr: "cannot be used for input",
+		},
+		{
+			name:       "second input missing",
+			args:       []string{"--info", input, filepath.Join(t.TempDir(), "nope.json")},
+			wantStderr: "does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, exitCode := runHbt(t, tt.args...)
+			if exitCode == 0 {
+				t.Fatal("expected non-zero exit")
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr %q does not contain %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
 func TestCLIListTags(t *testing.T) {
 	input := writeFlagsTestInput(t)
 
@@ -171,3 +321,425 @@ func TestCLIListTags(t *testing.T) {
 		t.Errorf("--list-tags output not sorted as expected:\ngot:\n%q\nwant:\n%q", stdout, want)
 	}
 }
+
+func TestCLIHBTJSONRoundTrip(t *testing.T) {
+	input := writeFlagsTestInput(t)
+	outFile := filepath.Join(t.TempDir(), "out.json")
+
+	// No -t or -f: hbt-json is detected from the -o extension on the way
+	// out and told apart from the Pinboard input by content on the way in.
+	_, stderr, exitCode := runHbt(t, "-o", outFile, input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+
+	fromPinboard, _, _ := runHbt(t, "-t", "yaml", input)
+	fromHBTJSON, stderr, exitCode := runHbt(t, "-t", "yaml", outFile)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if fromHBTJSON != fromPinboard {
+		t.Errorf("hbt-json round trip changed the collection:\nbefore:\n%s\nafter:\n%s", fromPinboard, fromHBTJSON)
+	}
+}
+
+func TestCLIYAMLRoundTrip(t *testing.T) {
+	input := writeFlagsTestInput(t)
+	outFile := filepath.Join(t.TempDir(), "out.yaml")
+
+	_, stderr, exitCode := runHbt(t, "-o", outFile, input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+
+	written, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("output file not written: %v", err)
+	}
+
+	reformatted, stderr, exitCode := runHbt(t, "-t", "yaml", outFile)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if reformatted != string(written) {
+		t.Errorf("yaml round trip not stable:\nbefore:\n%s\nafter:\n%s", written, reformatted)
+	}
+}
+
+func TestCLIDiff(t *testing.T) {
+	input := writeFlagsTestInput(t)
+
+	changed := filepath.Join(t.TempDir(), "changed.json")
+	const changedInput = `[
+  {"href": "https://example.com/b", "time": "2021-01-02T00:00:00Z", "description": "B", "tags": "keep later"},
+  {"href": "https://example.com/c", "time": "2021-01-03T00:00:00Z", "description": "C", "tags": ""}
+]`
+	if err := os.WriteFile(changed, []byte(changedInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, exitCode := runHbt(t, "diff", input, input)
+	if exitCode != 0 {
+		t.Fatalf("identical sources: exit %d, stderr: %s", exitCode, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("identical sources: unexpected output %q", stdout)
+	}
+
+	stdout, stderr, exitCode = runHbt(t, "diff", input, changed)
+	if exitCode != 1 {
+		t.Fatalf("differing sources: exit %d, want 1, stderr: %s", exitCode, stderr)
+	}
+	want := "+ https://example.com/c\n" +
+		"- https://example.com/a\n" +
+		"~ https://example.com/b\n" +
+		"    labels: +later\n"
+	if stdout != want {
+		t.Errorf("text report:\ngot:\n%s\nwant:\n%s", stdout, want)
+	}
+
+	stdout, _, exitCode = runHbt(t, "diff", "-t", "json", input, changed)
+	if exitCode != 1 {
+		t.Fatalf("json report: exit %d, want 1", exitCode)
+	}
+	if !strings.Contains(stdout, `"added": [`) || !strings.Contains(stdout, `"field": "labels"`) {
+		t.Errorf("unexpected json report:\n%s", stdout)
+	}
+
+	_, stderr, exitCode = runHbt(t, "diff", input)
+	if exitCode != 2 {
+		t.Errorf("one source: exit %d, want 2", exitCode)
+	}
+	if !strings.Contains(stderr, "exactly two sources") {
+		t.Errorf("one source: unexpected stderr %q", stderr)
+	}
+}
+
+func TestCLIMerge(t *testing.T) {
+	base := writeFlagsTestInput(t)
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// Ours drops the old tag from a; theirs deletes b.
+	ours := write("ours.json", `[
+  {"href": "https://example.com/a", "time": "2021-01-01T00:00:00Z", "description": "A", "tags": "shared"},
+  {"href": "https://example.com/b", "time": "2021-01-02T00:00:00Z", "description": "B", "tags": "keep"}
+]`)
+	theirs := write("theirs.json", `[
+  {"href": "https://example.com/a", "time": "2021-01-01T00:00:00Z", "description": "A", "tags": "old shared"}
+]`)
+
+	stdout, stderr, exitCode := runHbt(t, "merge", "--base", base, "-t", "yaml", ours, theirs)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if strings.Contains(stdout, "example.com/b") {
+		t.Error("entity deleted by theirs should be gone")
+	}
+	if strings.Contains(stdout, "old") || !strings.Contains(stdout, "shared") {
+		t.Errorf("label removed by ours should stay removed:\n%s", stdout)
+	}
+
+	// Both sides change the creation time of a, differently.
+	conflicting := write("conflicting.json", `[
+  {"href": "https://example.com/a", "time": "2021-01-05T00:00:00Z", "description": "A", "tags": "old shared"},
+  {"href": "https://example.com/b", "time": "2021-01-02T00:00:00Z", "description": "B", "tags": "keep"}
+]`)
+	other := write("other.json", `[
+  {"href": "https://example.com/a", "time": "2021-01-06T00:00:00Z", "description": "A", "tags": "old shared"},
+  {"href": "https://example.com/b", "time": "2021-01-02T00:00:00Z", "description": "B", "tags": "keep"}
+]`)
+
+	_, stderr, exitCode = runHbt(t, "merge", "--base", base, "-t", "yaml", conflicting, other)
+	if exitCode != 1 {
+		t.Fatalf("conflict: exit %d, want 1, stderr: %s", exitCode, stderr)
+	}
+	want := "conflict: https://example.com/a createdAt: base 2021-01-01T00:00:00Z, ours 2021-01-05T00:00:00Z, theirs 2021-01-06T00:00:00Z\n"
+	if stderr != want {
+		t.Errorf("stderr:\ngot:  %q\nwant: %q", stderr, want)
+	}
+
+	_, _, exitCode = runHbt(t, "merge", "--base", base, "--conflict", "theirs", "-t", "yaml", conflicting, other)
+	if exitCode != 0 {
+		t.Errorf("conflict resolved by theirs: exit %d, want 0", exitCode)
+	}
+
+	_, _, exitCode = runHbt(t, "merge", "-t", "yaml", ours, theirs)
+	if exitCode != 2 {
+		t.Errorf("missing --base: exit %d, want 2", exitCode)
+	}
+}
+
+func TestCLIWhere(t *testing.T) {
+	input := writeFlagsTestInput(t)
+
+	stdout, stderr, exitCode := runHbt(t, "--where", "label:keep or (label:old and created<2021-01-01)", "-t", "yaml", input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stdout, "example.com/b") || strings.Contains(stdout, "example.com/a") {
+		t.Errorf("expected only b:\n%s", stdout)
+	}
+
+	stdout, stderr, exitCode = runHbt(t, "--where", "not toread and not feed", "--info", input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if stdout != "Collection contains 2 entities\nQuery: 2 match, 0 do not, 0 unknown, 0 contradicted\n" {
+		t.Errorf("unexpected --info output: %q", stdout)
+	}
+
+	_, stderr, exitCode = runHbt(t, "--where", "label:", "--info", input)
+	if exitCode == 0 {
+		t.Fatal("expected non-zero exit for an invalid query")
+	}
+	if !strings.Contains(stderr, "missing value") {
+		t.Errorf("unexpected stderr: %q", stderr)
+	}
+}
+
+func TestCLIWhereUnknown(t *testing.T) {
+	// Markdown records no ToRead, so whether an entity is unread is unknown,
+	// and it is kept neither by toread nor by its negation.
+	dir := t.TempDir()
+	input := filepath.Join(dir, "notes.md")
+	const notes = "# January 3, 2021\n\n- [A](https://example.com/a)\n- [B](https://example.com/b)\n"
+	if err := os.WriteFile(input, []byte(notes), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, where := range []string{"toread", "not toread"} {
+		stdout, stderr, exitCode := runHbt(t, "--where", where, "--info", input)
+		if exitCode != 0 {
+			t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+		}
+		want := "Collection contains 0 entities\nQuery: 0 match, 0 do not, 2 unknown, 0 contradicted\n"
+		if stdout != want {
+			t.Errorf("--where %q:\ngot:\n%s\nwant:\n%s", where, stdout, want)
+		}
+	}
+}
+
+func TestCLIContradictions(t *testing.T) {
+	input := writeFlagsTestInput(t)
+	public := filepath.Join(t.TempDir(), "public.json")
+	const publicInput = `[{"href": "https://example.com/a", "time": "2021-01-01T00:00:00Z", "description": "A", "tags": "old shared", "shared": "yes"}]`
+	if err := os.WriteFile(public, []byte(publicInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, exitCode := runHbt(t, "-t", "json", input, public)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stderr, "contradicted: https://example.com/a: shared, written as false\n") {
+		t.Errorf("contradiction not listed:\n%s", stderr)
+	}
+	if strings.Contains(stdout, `"shared": "yes"`) {
+		t.Errorf("contradicted shared flag written as true:\n%s", stdout)
+	}
+
+	stdout, _, exitCode = runHbt(t, "--contradictions", "true", "-t", "json", input, public)
+	if exitCode != 0 || !strings.Contains(stdout, `"shared": "yes"`) {
+		t.Errorf("--contradictions true: exit %d, output:\n%s", exitCode, stdout)
+	}
+
+	_, stderr, exitCode = runHbt(t, "--contradictions", "refuse", "-t", "json", input, public)
+	if exitCode == 0 {
+		t.Fatal("--contradictions refuse: expected non-zero exit")
+	}
+	if !strings.Contains(stderr, "sources disagree on shared") {
+		t.Errorf("unexpected stderr: %q", stderr)
+	}
+
+	stdout, _, _ = runHbt(t, "--where", "shared", "--info", input, public)
+	if !strings.Contains(stdout, "1 contradicted") {
+		t.Errorf("query should find the contradiction: %q", stdout)
+	}
+
+	// Native formats keep the contradiction, whatever the policy.
+	stdout, stderr, exitCode = runHbt(t, "--contradictions", "refuse", "-t", "yaml", input, public)
+	if exitCode != 0 {
+		t.Fatalf("yaml: exit %d, stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stderr, "contradicted: https://example.com/a: shared\n") {
+		t.Errorf("yaml: contradiction not listed:\n%s", stderr)
+	}
+	if !strings.Contains(stdout, "version: 0.4.0") || !strings.Contains(stdout, "contradicted:\n    - shared") {
+		t.Errorf("yaml: contradiction not written:\n%s", stdout)
+	}
+
+	jsonl := filepath.Join(t.TempDir(), "contradicted.jsonl")
+	if _, stderr, exitCode := runHbt(t, "-t", "jsonl", "-o", jsonl, input, public); exitCode != 0 {
+		t.Fatalf("jsonl: exit %d, stderr: %s", exitCode, stderr)
+	}
+	stdout, stderr, exitCode = runHbt(t, "-t", "jsonl", jsonl)
+	if exitCode != 0 {
+		t.Fatalf("streamed jsonl: exit %d, stderr: %s", exitCode, stderr)
+	}
+	if stderr != "contradicted: https://example.com/a: shared\n" {
+		t.Errorf("streamed jsonl: unexpected stderr: %q", stderr)
+	}
+	if !strings.Contains(stdout, `"contradicted":["shared"]`) {
+		t.Errorf("streamed jsonl: contradiction not written:\n%s", stdout)
+	}
+}
+
+func TestCLIProvenance(t *testing.T) {
+	input := writeFlagsTestInput(t)
+	other := filepath.Join(t.TempDir(), "other.json")
+	const otherInput = `[{"href": "https://example.com/b", "time": "2021-01-02T00:00:00Z", "description": "B", "tags": "keep bad"}]`
+	if err := os.WriteFile(other, []byte(otherInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, exitCode := runHbt(t, "--trace-label", "bad", input, other)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	want := "https://example.com/b\n  " + other + " (json), record 1\n"
+	if stdout != want {
+		t.Errorf("--trace-label bad:\ngot:  %q\nwant: %q", stdout, want)
+	}
+
+	stdout, _, _ = runHbt(t, "--trace-label", "keep", input, other)
+	if !strings.Contains(stdout, input+" (json), record 2\n") || !strings.Contains(stdout, other+" (json), record 1\n") {
+		t.Errorf("--trace-label keep should list both inputs:\n%s", stdout)
+	}
+
+	stdout, stderr, exitCode = runHbt(t, "--provenance", "-t", "yaml", input, other)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stdout, "version: 0.2.0") || !strings.Contains(stdout, "provenance:") {
+		t.Errorf("--provenance output lacks provenance:\n%s", stdout)
+	}
+
+	stdout, _, _ = runHbt(t, "-t", "yaml", input, other)
+	if !strings.Contains(stdout, "version: 0.1.0") || strings.Contains(stdout, "provenance:") {
+		t.Errorf("output without --provenance:\n%s", stdout)
+	}
+}
+
+func TestCLIDedupe(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.json")
+	const dedupeInput = `[
+  {"href": "https://example.com/a", "time": "2021-01-01T00:00:00Z", "description": "A", "tags": "first"},
+  {"href": "http://www.example.com/a/?utm_source=feed", "time": "2021-01-02T00:00:00Z", "description": "A", "tags": "second"},
+  {"href": "https://example.com/b", "time": "2021-01-03T00:00:00Z", "description": "B", "tags": "keep"}
+]`
+	if err := os.WriteFile(input, []byte(dedupeInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, exitCode := runHbt(t, "dedupe", input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	want := "https://example.com/a\n  http://www.example.com/a/?utm_source=feed\nClusters: 1 (1 entities to absorb)\n"
+	if stdout != want {
+		t.Errorf("report:\ngot:  %q\nwant: %q", stdout, want)
+	}
+
+	stdout, _, _ = runHbt(t, "dedupe", "--rules", "www,slash", input)
+	if !strings.HasSuffix(stdout, "Clusters: 0 (0 entities to absorb)\n") {
+		t.Errorf("--rules www,slash should not ignore the scheme or query:\n%s", stdout)
+	}
+
+	stdout, stderr, exitCode = runHbt(t, "dedupe", "--apply", "-t", "yaml", input)
+	if exitCode != 0 {
+		t.Fatalf("--apply: exit %d, stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stdout, "length: 2") || !strings.Contains(stdout, "second") {
+		t.Errorf("--apply output:\n%s", stdout)
+	}
+
+	_, stderr, exitCode = runHbt(t, "dedupe", "--apply", input)
+	if exitCode == 0 || !strings.Contains(stderr, "output format") {
+		t.Errorf("--apply without -t: exit %d, stderr: %s", exitCode, stderr)
+	}
+}
+
+func TestCLILinks(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.md")
+	const linksInput = `# January 1, 2021
+
+- [A](https://example.com/a)
+  - [B](https://example.com/b)
+- [C](https://example.com/c)
+`
+	if err := os.WriteFile(input, []byte(linksInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without --child-links, nesting is read as edges, as before links.
+	stdout, stderr, exitCode := runHbt(t, "-t", "yaml", input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stdout, "version: 0.1.0") || strings.Contains(stdout, "links:") {
+		t.Errorf("YAML output without --child-links:\n%s", stdout)
+	}
+
+	stdout, stderr, exitCode = runHbt(t, "--child-links", "-t", "yaml", input)
+	if exitCode != 0 {
+		t.Fatalf("exit %d, stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stdout, "version: 0.3.0") || !strings.Contains(stdout, "kind: child-of") {
+		t.Errorf("YAML output lacks the child-of link:\n%s", stdout)
+	}
+
+	stdout, _, _ = runHbt(t, "--child-links", "-t", "dot", input)
+	if !strings.HasPrefix(stdout, "digraph hbt {") || !strings.Contains(stdout, `n0 -> n1 [label="child-of", dir=back];`) {
+		t.Errorf("DOT output does not draw the hierarchy:\n%s", stdout)
+	}
+
+	stdout, _, _ = runHbt(t, "--child-links", "-t", "markdown", input)
+	if stdout != linksInput+"\n" {
+		t.Errorf("Markdown round trip:\ngot:  %q\nwant: %q", stdout, linksInput+"\n")
+	}
+
+	stdout, _, _ = runHbt(t, "--child-links", "--where", "link:child-of", "--info", input)
+	if !strings.Contains(stdout, "Query: 1 match") {
+		t.Errorf("--where link:child-of: %q", stdout)
+	}
+}
+
+func TestCLIFoldersRoundTrip(t *testing.T) {
+	const bookmarks = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1609200000">Work</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1609200000">Go</A>
+    </DL><p>
+</DL><p>
+`
+	dir := t.TempDir()
+	input := filepath.Join(dir, "bookmarks.html")
+	if err := os.WriteFile(input, []byte(bookmarks), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"yaml", "hbt-json"} {
+		saved := filepath.Join(dir, "saved."+format)
+		if _, stderr, exitCode := runHbt(t, "-t", format, "-o", saved, input); exitCode != 0 {
+			t.Fatalf("%s: exit %d, stderr: %s", format, exitCode, stderr)
+		}
+		stdout, stderr, exitCode := runHbt(t, "-t", "html", "--html-folders", saved+":"+format)
+		if exitCode != 0 {
+			t.Fatalf("%s to html: exit %d, stderr: %s", format, exitCode, stderr)
+		}
+		if !strings.Contains(stdout, `<DT><H3 ADD_DATE="1609200000">Work</H3>`) {
+			t.Errorf("%s: folder lost in round trip:\n%s", format, stdout)
+		}
+	}
+}